@@ -6,21 +6,31 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"os/exec"
 	"strconv"
-	"strings"
+	"time"
 )
 
-type CmdError struct{ ExitCode int }
+type CmdError struct {
+	ExitCode int
+	// Kind is ErrCommandNotFound, ErrPermissionDenied or nil.
+	Kind error
+}
 
 func (e *CmdError) Error() string {
 	if e == nil {
 		return ""
 	}
+	if e.Kind != nil {
+		return fmt.Sprintf("exit code %d: %s", e.ExitCode, e.Kind)
+	}
 	return fmt.Sprintf("exit code %d", e.ExitCode)
 }
 
+func (e *CmdError) Is(target error) bool { return e.Kind != nil && target == e.Kind }
+
 const delim = "[ADB\x01CMD\x01DONE]"
 
 var delimb = []byte(delim)
@@ -29,39 +39,43 @@ var deliml = len(delimb)
 type ADB struct {
 	bin       string
 	dev       string
-	cmd       *exec.Cmd
+	spawn     func(args []string) (*shell, error)
+	proc      *shell
 	stdin     io.WriteCloser
 	stdout    *output
 	stderr    *output
 	buf       []byte
 	maxBuffer int
+
+	orient *Orientation
+	dpi    int
+	muted  map[Stream]int
+
+	root RootMethod
+	uid  int
+	su   string
+
+	timeout    time.Duration
+	middleware []Middleware
+	// streaming is set while ScreencapContinuous owns the session.
+	streaming int32
+	input     bool
+	points    []image.Point
+	script    string
 }
 
+// Devices returns the serials of all devices, see DeviceList for their
+// state.
 func Devices(executable string) ([]string, error) {
-	cmd := exec.Command(executable, "devices")
-	stdout, err := cmd.StdoutPipe()
+	devs, err := DeviceList(executable)
 	if err != nil {
 		return nil, err
 	}
-	s := bufio.NewScanner(stdout)
-	if err = cmd.Start(); err != nil {
-		return nil, err
+	list := make([]string, len(devs))
+	for i, d := range devs {
+		list[i] = d.Serial
 	}
-	list := make([]string, 0)
-
-	s.Scan()
-	for s.Scan() {
-		f := strings.Fields(s.Text())
-		if len(f) == 0 || f[0] == "" {
-			continue
-		}
-		list = append(list, f[0])
-	}
-	if s.Err() != nil {
-		return list, err
-	}
-
-	return list, cmd.Wait()
+	return list, nil
 }
 
 func New(executable string, device string, maxBuffer int) *ADB {
@@ -70,99 +84,190 @@ func New(executable string, device string, maxBuffer int) *ADB {
 		dev:       device,
 		buf:       make([]byte, 1024*4),
 		maxBuffer: maxBuffer,
+		uid:       -1,
 	}
 }
 
-func (adb *ADB) Init() error {
-	args := []string{"-s", adb.dev, "shell", "-T"}
-	if adb.dev == "" {
-		args = args[2:]
+// Session returns a new uninitialized client for the same device, root
+// method and middleware, useful for running things concurrently.
+func (adb *ADB) Session(maxBuffer int) *ADB {
+	s := New(adb.bin, adb.dev, maxBuffer)
+	s.root = adb.root
+	s.spawn = adb.spawn
+	s.middleware = append([]Middleware{}, adb.middleware...)
+	return s
+}
+
+// command creates an adb command targeting this client's device.
+func (adb *ADB) command(args ...string) *exec.Cmd {
+	if adb.dev != "" {
+		args = append([]string{"-s", adb.dev}, args...)
 	}
 	cmd := exec.Command(adb.bin, args...)
 	cmd.SysProcAttr = parentlessSysProc()
+	return cmd
+}
+
+// Serial returns the device serial this client was created for.
+func (adb *ADB) Serial() string { return adb.dev }
+
+// shell is a running remote shell process.
+type shell struct {
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr io.Reader
+	wait   func() error
+	kill   func() error
+}
+
+// execShell starts adb shell with the given args.
+func (adb *ADB) execShell(args []string) (*shell, error) {
+	cmd := adb.command(append([]string{"shell", "-T"}, args...)...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &shell{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		wait:   cmd.Wait,
+		kill:   cmd.Process.Kill,
+	}, nil
+}
+
+// stream starts a standalone shell command whose stdout is read by the
+// caller, stdin is closed and stderr discarded.
+func (adb *ADB) stream(args ...string) (*shell, error) {
+	spawn := adb.execShell
+	if adb.spawn != nil {
+		spawn = adb.spawn
+	}
+	sh, err := spawn(args)
+	if err != nil {
+		return nil, err
+	}
+	sh.stdin.Close()
+	go func() { _, _ = io.Copy(io.Discard, sh.stderr) }()
+	return sh, nil
+}
+
+func (adb *ADB) Init() error {
+	args, err := adb.initRoot()
+	if err != nil {
 		return err
 	}
 
-	adb.stdin = stdin
-	adb.stdout = newOutput(stdout, adb.maxBuffer)
-	adb.stderr = newOutput(stderr, adb.maxBuffer)
-	adb.cmd = cmd
+	spawn := adb.execShell
+	if adb.spawn != nil {
+		spawn = adb.spawn
+	}
+	sh, err := spawn(args)
+	if err != nil {
+		return err
+	}
+
+	adb.uid = -1
+	adb.stdin = sh.stdin
+	adb.stdout = newOutput(sh.stdout, adb.maxBuffer)
+	adb.stderr = newOutput(sh.stderr, adb.maxBuffer)
+	adb.proc = sh
 	return nil
 }
 
 func (adb *ADB) Close() error {
-	if adb.cmd == nil {
+	if adb.proc == nil {
 		return nil
 	}
 
 	adb.stdin.Close()
-	err := adb.cmd.Wait()
-	adb.cmd = nil
+	err := adb.proc.wait()
+	adb.proc = nil
 	return err
 }
 
+// send writes cmd followed by the exit code and delimiters to the shell.
+func (adb *ADB) send(cmd string) (err error) {
+	if _, err = fmt.Fprintln(adb.stdin, cmd); err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(adb.stdin, "printf '%%03d' $?; echo -en '%s'\n", delim); err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(adb.stdin, "echo -en '%s' >&2\n", delim); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recv reads the stdout of a single command and its exit code.
+func (adb *ADB) recv() ([]byte, int, error) {
+	d, err := adb.stdout.Next()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(d) < 3 {
+		return nil, 0, io.EOF
+	}
+	exit, err := strconv.Atoi(string(d[len(d)-3:]))
+	d = d[:len(d)-3]
+	if err != nil {
+		return nil, 0, &Error{Kind: ErrProtocol, Err: errors.New("could not parse exit code")}
+	}
+	return d, exit, nil
+}
+
+// SetTimeout sets the maximum duration of a single Run, the session is
+// reinitialized and an error matching ErrTimeout is returned when it is
+// exceeded. 0 disables the timeout (default).
+func (adb *ADB) SetTimeout(d time.Duration) { adb.timeout = d }
+
 // Run a command and pipe output to their respective writers.
 func (adb *ADB) Run(cmd string, stdout, stderr io.Writer) error {
-	done := make(chan error, 1)
+	return adb.intercept(cmd, stdout, stderr, adb.run)
+}
+
+// maxErrOutput is the amount of stderr kept for error classification.
+const maxErrOutput = 512
+
+func (adb *ADB) run(cmd string, stdout, stderr io.Writer) error {
+	var exit int
+	var errOutput []byte
+	var transport bool
+
+	done := make(chan error, 3)
 	go func() {
-		done <- func() (err error) {
-			if _, err = fmt.Fprintln(adb.stdin, cmd); err != nil {
-				return err
-			}
-			if _, err = fmt.Fprintf(adb.stdin, "printf '%%03d' $?; echo -en '%s'\n", delim); err != nil {
-				return err
-			}
-			if _, err = fmt.Fprintf(adb.stdin, "echo -en '%s' >&2\n", delim); err != nil {
-				return err
-			}
-			return nil
-		}()
+		done <- adb.send(cmd)
 	}()
 
 	go func() {
 		done <- func() error {
-			d, err := adb.stdout.Next()
+			d, code, err := adb.recv()
 			if err != nil {
 				return err
 			}
-			if len(d) < 3 {
-				return io.EOF
-			}
-			exit, err := strconv.Atoi(string(d[len(d)-3:]))
-			d = d[:len(d)-3]
-			if err != nil {
-				return fmt.Errorf("something went wrong: could not parse exit code")
-			}
-
-			var exitErr error
-			if exit != 0 {
-				exitErr = &CmdError{exit}
-			}
+			exit = code
 
 			if stdout == nil {
-				return exitErr
-			}
-
-			if _, err = stdout.Write(d); err != nil {
-				return err
+				return nil
 			}
 
-			return exitErr
+			_, err = stdout.Write(d)
+			return err
 		}()
 	}()
 
@@ -173,6 +278,12 @@ func (adb *ADB) Run(cmd string, stdout, stderr io.Writer) error {
 				return err
 			}
 
+			errOutput = d
+			if len(errOutput) > maxErrOutput {
+				errOutput = errOutput[len(errOutput)-maxErrOutput:]
+			}
+			errOutput = append([]byte{}, errOutput...)
+
 			if stderr == nil {
 				return nil
 			}
@@ -181,22 +292,87 @@ func (adb *ADB) Run(cmd string, stdout, stderr io.Writer) error {
 		}()
 	}()
 
+	var timeout <-chan time.Time
+	if adb.timeout > 0 {
+		t := time.NewTimer(adb.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
 	var err error
-	for i := 0; i < 3; i++ {
-		if e := <-done; e != nil && err == nil {
-			err = e
+	var timedOut bool
+	for i := 0; i < 3; {
+		select {
+		case e := <-done:
+			i++
+			if e != nil && err == nil {
+				err = e
+			}
+		case <-timeout:
+			timeout, timedOut = nil, true
+			if adb.proc != nil {
+				_ = adb.proc.kill()
+			}
+		}
+	}
+
+	switch {
+	case timedOut:
+		err = &Error{Kind: ErrTimeout, Err: err}
+		transport = true
+	case err != nil:
+		var e *Error
+		if !errors.As(err, &e) {
+			err = transportError(err, string(errOutput))
 		}
+		transport = true
+	case exit != 0:
+		err = &CmdError{ExitCode: exit, Kind: exitKind(exit, string(errOutput))}
 	}
 
-	var ce *CmdError
-	if err != nil && !errors.As(err, &ce) {
-		_ = adb.Close()
-		_ = adb.Init()
+	if transport {
+		adb.reconnect()
 	}
 
 	return err
 }
 
+// runInput runs a command that is marked as Call.Input for middleware,
+// points are the coordinates as passed to the input method.
+func (adb *ADB) runInput(cmd string, points ...image.Point) error {
+	return adb.runInputTo(cmd, nil, nil, points...)
+}
+
+// runInputTo is runInput writing the command's output to stdout and stderr.
+func (adb *ADB) runInputTo(cmd string, stdout, stderr io.Writer, points ...image.Point) error {
+	return adb.runInputWith(cmd, stdout, stderr, adb.run, points...)
+}
+
+// runInputApply is runInput calling apply after the command succeeded,
+// apply is not called when middleware (e.g. DryRun) drops the command.
+func (adb *ADB) runInputApply(cmd string, apply func()) error {
+	return adb.runInputWith(cmd, nil, nil, func(cmd string, stdout, stderr io.Writer) error {
+		err := adb.run(cmd, stdout, stderr)
+		if err == nil {
+			apply()
+		}
+		return err
+	})
+}
+
+// runInputWith is runInputTo executing the command with run once it passed
+// the middleware chain.
+func (adb *ADB) runInputWith(cmd string, stdout, stderr io.Writer, run func(cmd string, stdout, stderr io.Writer) error, points ...image.Point) error {
+	adb.input, adb.points = true, points
+	defer func() { adb.input, adb.points, adb.script = false, nil, "" }()
+	return adb.intercept(cmd, stdout, stderr, run)
+}
+
+func (adb *ADB) reconnect() {
+	_ = adb.Close()
+	_ = adb.Init()
+}
+
 type output struct {
 	io.Reader
 	*bufio.Scanner
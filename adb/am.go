@@ -8,11 +8,19 @@ import (
 )
 
 func (adb *ADB) AmStart(pkg, activity string) error {
-	return adb.Run(fmt.Sprintf("am start -n %s/%s.%s >/dev/null 2>&1", pkg, pkg, activity), nil, nil)
+	return adb.runInput(fmt.Sprintf("am start -n %s/%s.%s >/dev/null 2>&1", pkg, pkg, activity))
 }
 
 func (adb *ADB) AmKill(pkg string) error {
-	return adb.Run(fmt.Sprintf("am force-stop %s >/dev/null 2>&1", pkg), nil, nil)
+	return adb.runInput(fmt.Sprintf("am force-stop %s >/dev/null 2>&1", pkg))
+}
+
+// AmRestart force stops and restarts the given package.
+func (adb *ADB) AmRestart(pkg, activity string) error {
+	if err := adb.AmKill(pkg); err != nil {
+		return err
+	}
+	return adb.AmStart(pkg, activity)
 }
 
 func (adb *ADB) AmEnsure(pkg, activity string) error {
@@ -23,15 +31,7 @@ func (adb *ADB) AmEnsure(pkg, activity string) error {
 	return adb.AmStart(pkg, activity)
 }
 
-var activityRE *regexp.Regexp
-
-func getActivityRE() *regexp.Regexp {
-	if activityRE != nil {
-		return activityRE
-	}
-	activityRE = regexp.MustCompile(`\d+:([^/]+)/[^\s]+\s+\(top-activity\)`)
-	return activityRE
-}
+var activityRE = regexp.MustCompile(`\d+:([^/]+)/[^\s]+\s+\(top-activity\)`)
 
 func (adb *ADB) TopActivity() (pkg string, err error) {
 	buf := bytes.NewBuffer(nil)
@@ -40,7 +40,7 @@ func (adb *ADB) TopActivity() (pkg string, err error) {
 		return
 	}
 	d := strings.TrimSpace(buf.String())
-	res := getActivityRE().FindStringSubmatch(d)
+	res := activityRE.FindStringSubmatch(d)
 	if len(res) == 2 {
 		pkg = res[1]
 	}
@@ -0,0 +1,270 @@
+package adb
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type BatchResult struct {
+	Cmd      string
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// Err returns a *CmdError if the command exited with a non-zero code.
+func (b BatchResult) Err() error {
+	if b.ExitCode == 0 {
+		return nil
+	}
+	return &CmdError{ExitCode: b.ExitCode, Kind: exitKind(b.ExitCode, string(b.Stderr))}
+}
+
+// Batch writes all commands to the shell at once and collects their
+// output and exit codes in order, saving a round-trip per command.
+// The returned error is only non-nil for transport errors, check
+// BatchResult.Err for each command.
+// Commands can not contain newlines, use a Script for multi-line commands.
+// The batch is marked as Call.Input since its commands are not inspected,
+// when it is dropped by middleware (e.g. DryRun) the results are empty with
+// a zero exit code.
+func (adb *ADB) Batch(cmds ...string) ([]BatchResult, error) {
+	for _, cmd := range cmds {
+		if strings.ContainsAny(cmd, "\r\n") {
+			return nil, fmt.Errorf("batch command contains a newline: %q", cmd)
+		}
+	}
+
+	var results []BatchResult
+	ran := false
+	joined := strings.Join(cmds, "\n")
+	adb.input = true
+	err := adb.intercept(joined, nil, nil, func(cmd string, stdout, stderr io.Writer) error {
+		if cmd != joined {
+			cmds = strings.Split(cmd, "\n")
+		}
+		var err error
+		ran = true
+		results, err = adb.batch(cmds)
+		for _, r := range results {
+			if stdout != nil {
+				_, _ = stdout.Write(r.Stdout)
+			}
+			if stderr != nil {
+				_, _ = stderr.Write(r.Stderr)
+			}
+		}
+		return err
+	})
+	adb.input = false
+	if err == nil && !ran {
+		results = make([]BatchResult, len(cmds))
+		for i := range cmds {
+			results[i].Cmd = cmds[i]
+		}
+	}
+	return results, err
+}
+
+func (adb *ADB) batch(cmds []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(cmds))
+	for i := range cmds {
+		results[i].Cmd = cmds[i]
+	}
+
+	done := make(chan error, 3)
+	go func() {
+		done <- func() error {
+			for _, cmd := range cmds {
+				if err := adb.send(cmd); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+	}()
+
+	go func() {
+		done <- func() error {
+			for i := range cmds {
+				d, exit, err := adb.recv()
+				if err != nil {
+					return err
+				}
+				results[i].Stdout = append([]byte{}, d...)
+				results[i].ExitCode = exit
+			}
+			return nil
+		}()
+	}()
+
+	go func() {
+		done <- func() error {
+			for i := range cmds {
+				d, err := adb.stderr.Next()
+				if err != nil {
+					return err
+				}
+				results[i].Stderr = append([]byte{}, d...)
+			}
+			return nil
+		}()
+	}()
+
+	var err error
+	for i := 0; i < 3; i++ {
+		if e := <-done; e != nil && err == nil {
+			err = e
+		}
+	}
+
+	if err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			err = transportError(err, "")
+		}
+		adb.reconnect()
+	}
+
+	return results, err
+}
+
+// Script is a shell script that is pushed to and executed on the device
+// so timing between its commands does not depend on the adb connection.
+// Coordinates are translated when the script is run, see
+// SetOrientationAware.
+type Script struct {
+	lines []func(adb *ADB) string
+	// points touched by the script, see Call.Points
+	points []image.Point
+}
+
+func NewScript() *Script { return &Script{} }
+
+func (s *Script) add(f func(adb *ADB) string) *Script {
+	s.lines = append(s.lines, f)
+	return s
+}
+
+// Raw appends a shell command.
+func (s *Script) Raw(cmd string) *Script {
+	return s.add(func(*ADB) string { return cmd })
+}
+
+func (s *Script) Tap(x, y int) *Script {
+	s.points = append(s.points, image.Pt(x, y))
+	return s.add(func(adb *ADB) string {
+		x, y := adb.xy(x, y)
+		return fmt.Sprintf("input tap %d %d", x, y)
+	})
+}
+
+func (s *Script) Drag(x0, y0, x1, y1 int, dur time.Duration) *Script {
+	s.points = append(s.points, image.Pt(x0, y0), image.Pt(x1, y1))
+	return s.add(func(adb *ADB) string {
+		x0, y0 := adb.xy(x0, y0)
+		x1, y1 := adb.xy(x1, y1)
+		return fmt.Sprintf("input swipe %d %d %d %d %d", x0, y0, x1, y1, dur.Milliseconds())
+	})
+}
+
+func (s *Script) Hold(x, y int, dur time.Duration) *Script {
+	return s.Drag(x, y, x, y, dur)
+}
+
+func (s *Script) Text(str string) *Script {
+	return s.Raw(fmt.Sprintf("input text %s", Quote(str)))
+}
+
+func (s *Script) KeyEvent(keycode string) *Script {
+	return s.Raw(fmt.Sprintf("input keyevent %s", Quote(keycode)))
+}
+
+func (s *Script) Sleep(d time.Duration) *Script {
+	return s.Raw("sleep " + strconv.FormatFloat(d.Seconds(), 'f', 3, 64))
+}
+
+// String renders the script without coordinate translation.
+func (s *Script) String() string { return s.render(&ADB{}) }
+
+func (s *Script) render(adb *ADB) string {
+	l := make([]string, len(s.lines))
+	for i, f := range s.lines {
+		l[i] = f(adb)
+	}
+	return strings.Join(l, "\n") + "\n"
+}
+
+// writeChunk is the amount of bytes written per command.
+const writeChunk = 1024 * 32
+
+// writeFile creates or truncates path on the device and writes data to it
+// in base64 encoded chunks using run.
+func writeFile(path string, data []byte, run func(cmd string) error) error {
+	op := ">"
+	for len(data) != 0 || op == ">" {
+		n := writeChunk
+		if n > len(data) {
+			n = len(data)
+		}
+		enc := base64.StdEncoding.EncodeToString(data[:n])
+		data = data[n:]
+		cmd := fmt.Sprintf("echo %s | base64 -d %s %s", Quote(enc), op, Quote(path))
+		if err := run(cmd); err != nil {
+			return err
+		}
+		op = ">>"
+	}
+	return nil
+}
+
+var scriptCounter uint32
+
+// RunScript pushes s to /data/local/tmp, executes it and removes it.
+// Output of the script is discarded, a non-zero exit code of its last
+// command is returned as a *CmdError.
+func (adb *ADB) RunScript(s *Script) error {
+	return adb.runScript(s, func(cmd string) (string, error) { return cmd, nil })
+}
+
+// RunScriptPrivileged is RunScript executing the script as root,
+// see RunPrivileged.
+func (adb *ADB) RunScriptPrivileged(s *Script) error {
+	return adb.runScript(s, adb.privileged)
+}
+
+func (adb *ADB) runScript(s *Script, wrap func(cmd string) (string, error)) error {
+	path := fmt.Sprintf(
+		"/data/local/tmp/autodroid-%d-%d.sh",
+		time.Now().UnixNano(),
+		atomic.AddUint32(&scriptCounter, 1),
+	)
+	script := s.render(adb)
+	cmd, err := wrap(fmt.Sprintf("sh %s >/dev/null 2>&1", Quote(path)))
+	if err != nil {
+		return err
+	}
+
+	// the script is only pushed once the call passed the middleware chain
+	adb.script = script
+	return adb.runInputWith(cmd, nil, nil, func(cmd string, stdout, stderr io.Writer) error {
+		err := writeFile(path, []byte(script), func(cmd string) error {
+			return adb.run(cmd, nil, nil)
+		})
+		if err != nil {
+			return err
+		}
+		err = adb.run(cmd, stdout, stderr)
+		if rerr := adb.run(fmt.Sprintf("rm -f %s", Quote(path)), nil, nil); err == nil {
+			err = rerr
+		}
+		return err
+	}, s.points...)
+}
@@ -0,0 +1,266 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type BatteryStatus int
+
+const (
+	BatteryStatusUnknown     BatteryStatus = 1
+	BatteryStatusCharging    BatteryStatus = 2
+	BatteryStatusDischarging BatteryStatus = 3
+	BatteryStatusNotCharging BatteryStatus = 4
+	BatteryStatusFull        BatteryStatus = 5
+)
+
+func (s BatteryStatus) String() string {
+	switch s {
+	case BatteryStatusCharging:
+		return "charging"
+	case BatteryStatusDischarging:
+		return "discharging"
+	case BatteryStatusNotCharging:
+		return "not charging"
+	case BatteryStatusFull:
+		return "full"
+	}
+	return "unknown"
+}
+
+type BatteryHealth int
+
+const (
+	BatteryHealthUnknown            BatteryHealth = 1
+	BatteryHealthGood               BatteryHealth = 2
+	BatteryHealthOverheat           BatteryHealth = 3
+	BatteryHealthDead               BatteryHealth = 4
+	BatteryHealthOverVoltage        BatteryHealth = 5
+	BatteryHealthUnspecifiedFailure BatteryHealth = 6
+	BatteryHealthCold               BatteryHealth = 7
+)
+
+func (h BatteryHealth) String() string {
+	switch h {
+	case BatteryHealthGood:
+		return "good"
+	case BatteryHealthOverheat:
+		return "overheat"
+	case BatteryHealthDead:
+		return "dead"
+	case BatteryHealthOverVoltage:
+		return "over voltage"
+	case BatteryHealthUnspecifiedFailure:
+		return "failure"
+	case BatteryHealthCold:
+		return "cold"
+	}
+	return "unknown"
+}
+
+type Plugged string
+
+const (
+	PluggedNone     Plugged = ""
+	PluggedAC       Plugged = "ac"
+	PluggedUSB      Plugged = "usb"
+	PluggedWireless Plugged = "wireless"
+)
+
+type Battery struct {
+	Present bool
+	Level   int
+	Scale   int
+	Status  BatteryStatus
+	Health  BatteryHealth
+	Plugged Plugged
+	// Temperature in °C.
+	Temperature float64
+	// Voltage in mV.
+	Voltage    int
+	Technology string
+}
+
+// Percentage returns the charge level in the range [0, 100].
+func (b Battery) Percentage() float64 {
+	if b.Scale <= 0 {
+		return float64(b.Level)
+	}
+	return float64(b.Level) * 100 / float64(b.Scale)
+}
+
+func parseKV(d []byte, sep string) map[string]string {
+	m := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(d))
+	for s.Scan() {
+		p := strings.SplitN(s.Text(), sep, 2)
+		if len(p) != 2 {
+			continue
+		}
+		m[strings.TrimSpace(p[0])] = strings.TrimSpace(p[1])
+	}
+	return m
+}
+
+func (adb *ADB) Battery() (Battery, error) {
+	var b Battery
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("dumpsys battery", buf, nil); err != nil {
+		return b, err
+	}
+
+	kv := parseKV(buf.Bytes(), ":")
+	atoi := func(k string) int { v, _ := strconv.Atoi(kv[k]); return v }
+
+	b.Present = kv["present"] == "true"
+	b.Level = atoi("level")
+	b.Scale = atoi("scale")
+	b.Status = BatteryStatus(atoi("status"))
+	b.Health = BatteryHealth(atoi("health"))
+	b.Temperature = float64(atoi("temperature")) / 10
+	b.Voltage = atoi("voltage")
+	b.Technology = kv["technology"]
+	switch {
+	case kv["AC powered"] == "true":
+		b.Plugged = PluggedAC
+	case kv["USB powered"] == "true":
+		b.Plugged = PluggedUSB
+	case kv["Wireless powered"] == "true":
+		b.Plugged = PluggedWireless
+	}
+
+	return b, nil
+}
+
+// SetBattery fakes a battery property (e.g. level, status, ac, usb) until
+// ResetBattery is called.
+func (adb *ADB) SetBattery(key string, value int) error {
+	return adb.runInput(fmt.Sprintf("dumpsys battery set %s %d >/dev/null 2>&1", Quote(key), value))
+}
+
+func (adb *ADB) SetBatteryLevel(level int) error {
+	return adb.SetBattery("level", level)
+}
+
+func (adb *ADB) SetBatteryStatus(status BatteryStatus) error {
+	return adb.SetBattery("status", int(status))
+}
+
+// UnplugBattery fakes a disconnected charger.
+func (adb *ADB) UnplugBattery() error {
+	return adb.runInput("dumpsys battery unplug >/dev/null 2>&1")
+}
+
+// ResetBattery undoes SetBattery and UnplugBattery.
+func (adb *ADB) ResetBattery() error {
+	return adb.runInput("dumpsys battery reset >/dev/null 2>&1")
+}
+
+type ThermalStatus int
+
+const (
+	ThermalStatusNone ThermalStatus = iota
+	ThermalStatusLight
+	ThermalStatusModerate
+	ThermalStatusSevere
+	ThermalStatusCritical
+	ThermalStatusEmergency
+	ThermalStatusShutdown
+)
+
+func (t ThermalStatus) String() string {
+	switch t {
+	case ThermalStatusNone:
+		return "none"
+	case ThermalStatusLight:
+		return "light"
+	case ThermalStatusModerate:
+		return "moderate"
+	case ThermalStatusSevere:
+		return "severe"
+	case ThermalStatusCritical:
+		return "critical"
+	case ThermalStatusEmergency:
+		return "emergency"
+	case ThermalStatusShutdown:
+		return "shutdown"
+	}
+	return "unknown"
+}
+
+// Throttling reports whether the device is throttling performance.
+func (t ThermalStatus) Throttling() bool { return t >= ThermalStatusModerate }
+
+type Temperature struct {
+	Name   string
+	Type   int
+	Value  float64
+	Status ThermalStatus
+}
+
+type Thermal struct {
+	Status       ThermalStatus
+	Temperatures []Temperature
+}
+
+// Max returns the highest sensor temperature.
+func (t Thermal) Max() (Temperature, bool) {
+	var m Temperature
+	for i, tmp := range t.Temperatures {
+		if i == 0 || tmp.Value > m.Value {
+			m = tmp
+		}
+	}
+	return m, len(t.Temperatures) != 0
+}
+
+var (
+	thermalStatusRE = regexp.MustCompile(`(?m)^\s*Thermal Status:\s*(\d+)`)
+	temperatureRE   = regexp.MustCompile(
+		`Temperature\{mValue=([-\d.]+), mType=(-?\d+), mName=([^,]*), mStatus=(\d+)\}`,
+	)
+)
+
+// Thermal reads the thermal service status and the cached sensor
+// temperatures, requires android 10+.
+func (adb *ADB) Thermal() (Thermal, error) {
+	var t Thermal
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("dumpsys thermalservice", buf, nil); err != nil {
+		return t, err
+	}
+	d := buf.String()
+	if res := thermalStatusRE.FindStringSubmatch(d); len(res) == 2 {
+		v, _ := strconv.Atoi(res[1])
+		t.Status = ThermalStatus(v)
+	}
+
+	// Only use the first block (cached temperatures), the HAL block repeats them.
+	if i := strings.Index(d, "Current temperatures from HAL"); i > 0 {
+		if c := strings.Index(d, "Cached temperatures"); c >= 0 && c < i {
+			d = d[:i]
+		}
+	}
+
+	seen := make(map[string]struct{})
+	for _, res := range temperatureRE.FindAllStringSubmatch(d, -1) {
+		if _, ok := seen[res[3]]; ok {
+			continue
+		}
+		seen[res[3]] = struct{}{}
+		var tmp Temperature
+		tmp.Value, _ = strconv.ParseFloat(res[1], 64)
+		tmp.Type, _ = strconv.Atoi(res[2])
+		tmp.Name = res[3]
+		st, _ := strconv.Atoi(res[4])
+		tmp.Status = ThermalStatus(st)
+		t.Temperatures = append(t.Temperatures, tmp)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,144 @@
+package adb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBattery(t *testing.T) {
+	tests := []struct {
+		dump string
+		exp  Battery
+	}{
+		{
+			// pixel 4a, android 12
+			`Current Battery Service state:
+  AC powered: false
+  USB powered: true
+  Wireless powered: false
+  Max charging current: 500000
+  Max charging voltage: 5000000
+  Charge counter: 2906000
+  status: 2
+  health: 2
+  present: true
+  level: 87
+  scale: 100
+  voltage: 4312
+  temperature: 284
+  technology: Li-ion
+`,
+			Battery{
+				Present:     true,
+				Level:       87,
+				Scale:       100,
+				Status:      BatteryStatusCharging,
+				Health:      BatteryHealthGood,
+				Plugged:     PluggedUSB,
+				Temperature: 28.4,
+				Voltage:     4312,
+				Technology:  "Li-ion",
+			},
+		},
+		{
+			// emulator after dumpsys battery unplug and set level 5
+			`Current Battery Service state:
+  (UPDATES STOPPED -- use 'reset' to restart)
+  AC powered: false
+  USB powered: false
+  Wireless powered: false
+  Max charging current: 0
+  Max charging voltage: 0
+  Charge counter: 10000
+  status: 3
+  health: 2
+  present: true
+  level: 5
+  scale: 100
+  voltage: 5000
+  temperature: -12
+  technology: Li-ion
+`,
+			Battery{
+				Present:     true,
+				Level:       5,
+				Scale:       100,
+				Status:      BatteryStatusDischarging,
+				Health:      BatteryHealthGood,
+				Temperature: -1.2,
+				Voltage:     5000,
+				Technology:  "Li-ion",
+			},
+		},
+	}
+	for _, test := range tests {
+		d := NewFakeDevice("fake", nil)
+		d.Handle("dumpsys", fakeOutput(test.dump))
+		c := fakeClient(t, d)
+		b, err := c.Battery()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != test.exp {
+			t.Errorf("expected %+v, got %+v", test.exp, b)
+		}
+	}
+}
+
+// pixel 4a, android 12, trimmed
+const thermalDump = `IsStatusOverride: false
+ThermalEventListeners:
+	callbacks: 1
+	killed: false
+	broadcasts count: -1
+ThermalStatusListeners:
+	callbacks: 1
+	killed: false
+	broadcasts count: -1
+Thermal Status: 2
+Cached temperatures:
+	Temperature{mValue=31.8, mType=2, mName=battery, mStatus=0}
+	Temperature{mValue=44.6, mType=0, mName=cpu1-gold-usr, mStatus=2}
+	Temperature{mValue=38.5, mType=0, mName=cpu0-silver-usr, mStatus=0}
+	Temperature{mValue=-1.5, mType=3, mName=skin-therm, mStatus=0}
+HAL Ready: true
+HAL connection:
+	ThermalHAL 2.0 connected: yes
+Current temperatures from HAL:
+	Temperature{mValue=31.9, mType=2, mName=battery, mStatus=0}
+	Temperature{mValue=45.1, mType=0, mName=cpu1-gold-usr, mStatus=2}
+	Temperature{mValue=38.2, mType=0, mName=cpu0-silver-usr, mStatus=0}
+	Temperature{mValue=-1.4, mType=3, mName=skin-therm, mStatus=0}
+Current cooling devices from HAL:
+	CoolingDevice{mValue=0, mType=2, mName=cpu0}
+	CoolingDevice{mValue=3, mType=2, mName=cpu1}
+`
+
+func TestThermal(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.Handle("dumpsys", fakeOutput(thermalDump))
+	c := fakeClient(t, d)
+
+	th, err := c.Thermal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Thermal{
+		Status: ThermalStatusModerate,
+		Temperatures: []Temperature{
+			{Name: "battery", Type: 2, Value: 31.8, Status: ThermalStatusNone},
+			{Name: "cpu1-gold-usr", Type: 0, Value: 44.6, Status: ThermalStatusModerate},
+			{Name: "cpu0-silver-usr", Type: 0, Value: 38.5, Status: ThermalStatusNone},
+			{Name: "skin-therm", Type: 3, Value: -1.5, Status: ThermalStatusNone},
+		},
+	}
+	if !reflect.DeepEqual(th, exp) {
+		t.Errorf("expected %+v, got %+v", exp, th)
+	}
+	if !th.Status.Throttling() {
+		t.Error("expected throttling")
+	}
+	if m, ok := th.Max(); !ok || m.Name != "cpu1-gold-usr" {
+		t.Errorf("expected max cpu1-gold-usr, got %+v, %t", m, ok)
+	}
+}
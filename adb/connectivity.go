@@ -0,0 +1,122 @@
+package adb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Connectivity struct {
+	Wifi         bool
+	MobileData   bool
+	AirplaneMode bool
+	Bluetooth    bool
+	// Online reports whether there is an active default network.
+	Online bool
+}
+
+func enableDisable(on bool) string {
+	if on {
+		return "enable"
+	}
+	return "disable"
+}
+
+// grep runs cmd and returns the trimmed output, a grep without matches is
+// not considered an error.
+func (adb *ADB) grep(cmd string) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run(cmd, buf, nil)
+	var ce *CmdError
+	if err != nil && !(errors.As(err, &ce) && ce.ExitCode == 1) {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func (adb *ADB) Wifi() (bool, error) {
+	v, err := adb.grep("dumpsys wifi 2>&1 | grep -m 1 '^Wi-Fi is'")
+	return v == "Wi-Fi is enabled", err
+}
+
+func (adb *ADB) SetWifi(on bool) error {
+	return adb.runInput(fmt.Sprintf("svc wifi %s >/dev/null 2>&1", enableDisable(on)))
+}
+
+func (adb *ADB) MobileData() (bool, error) {
+	v, err := adb.Setting(Global, "mobile_data")
+	return v == "1", err
+}
+
+func (adb *ADB) SetMobileData(on bool) error {
+	return adb.runInput(fmt.Sprintf("svc data %s >/dev/null 2>&1", enableDisable(on)))
+}
+
+func (adb *ADB) AirplaneMode() (bool, error) {
+	v, err := adb.Setting(Global, "airplane_mode_on")
+	return v == "1", err
+}
+
+// SetAirplaneMode requires android 11+.
+func (adb *ADB) SetAirplaneMode(on bool) error {
+	return adb.runInput(fmt.Sprintf("cmd connectivity airplane-mode %s >/dev/null 2>&1", enableDisable(on)))
+}
+
+func (adb *ADB) Bluetooth() (bool, error) {
+	v, err := adb.grep("dumpsys bluetooth_manager 2>&1 | grep -m 1 -E '^\\s*enabled:'")
+	return strings.HasSuffix(v, "true"), err
+}
+
+// SetBluetooth requires android 12+.
+func (adb *ADB) SetBluetooth(on bool) error {
+	return adb.runInput(fmt.Sprintf("cmd bluetooth_manager %s >/dev/null 2>&1", enableDisable(on)))
+}
+
+// Online reports whether the device has an active default network.
+func (adb *ADB) Online() (bool, error) {
+	v, err := adb.grep("dumpsys connectivity 2>&1 | grep -m 1 'Active default network:'")
+	if err != nil {
+		return false, err
+	}
+	v = strings.TrimSpace(strings.TrimPrefix(v, "Active default network:"))
+	return v != "" && v != "none", nil
+}
+
+func (adb *ADB) Connectivity() (Connectivity, error) {
+	var c Connectivity
+	var err error
+	if c.Wifi, err = adb.Wifi(); err != nil {
+		return c, err
+	}
+	if c.MobileData, err = adb.MobileData(); err != nil {
+		return c, err
+	}
+	if c.AirplaneMode, err = adb.AirplaneMode(); err != nil {
+		return c, err
+	}
+	if c.Bluetooth, err = adb.Bluetooth(); err != nil {
+		return c, err
+	}
+	c.Online, err = adb.Online()
+	return c, err
+}
+
+// WaitOnline polls Online until it equals online or timeout expires.
+func (adb *ADB) WaitOnline(online bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		v, err := adb.Online()
+		if err != nil {
+			return err
+		}
+		if v == online {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(time.Millisecond * 250)
+	}
+}
@@ -0,0 +1,122 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	contentRowRE   = regexp.MustCompile(`^Row: \d+ (.*)$`)
+	contentFieldRE = regexp.MustCompile(`(?:^|, )([\w.]+)=`)
+)
+
+// ContentQuery queries a content provider, projection, where and sort are
+// optional. Values containing ', <key>=' or newlines are parsed
+// incorrectly due to the output format of the content command.
+func (adb *ADB) ContentQuery(uri string, projection []string, where, sort string) ([]map[string]string, error) {
+	cmd := fmt.Sprintf("content query --uri %s", Quote(uri))
+	if len(projection) != 0 {
+		cmd += " --projection " + Quote(strings.Join(projection, ":"))
+	}
+	if where != "" {
+		cmd += " --where " + Quote(where)
+	}
+	if sort != "" {
+		cmd += " --sort " + Quote(sort)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run(cmd, buf, nil); err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]string, 0)
+	s := bufio.NewScanner(buf)
+	s.Buffer(make([]byte, 0, 1024*64), 1024*1024)
+	for s.Scan() {
+		res := contentRowRE.FindStringSubmatch(s.Text())
+		if len(res) != 2 {
+			continue
+		}
+		l := res[1]
+		row := make(map[string]string)
+		idx := contentFieldRE.FindAllStringSubmatchIndex(l, -1)
+		for i, m := range idx {
+			end := len(l)
+			if i < len(idx)-1 {
+				end = idx[i+1][0]
+			}
+			row[l[m[2]:m[3]]] = l[m[1]:end]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, s.Err()
+}
+
+func contentBinds(values map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var typ, v string
+		switch val := values[k].(type) {
+		case string:
+			typ, v = "s", val
+		case bool:
+			typ, v = "b", fmt.Sprint(val)
+		case int, int32:
+			typ, v = "i", fmt.Sprint(val)
+		case int64:
+			typ, v = "l", fmt.Sprint(val)
+		case float32:
+			typ, v = "f", fmt.Sprint(val)
+		case float64:
+			typ, v = "d", fmt.Sprint(val)
+		case nil:
+			typ, v = "n", ""
+		default:
+			return "", fmt.Errorf("content value '%s': unsupported type %T", k, val)
+		}
+		b = append(b, "--bind "+Quote(fmt.Sprintf("%s:%s:%s", k, typ, v)))
+	}
+	return strings.Join(b, " "), nil
+}
+
+// ContentInsert inserts a row, supported value types are
+// string, bool, int, int32, int64, float32, float64 and nil.
+func (adb *ADB) ContentInsert(uri string, values map[string]interface{}) error {
+	binds, err := contentBinds(values)
+	if err != nil {
+		return err
+	}
+	return adb.runInput(fmt.Sprintf("content insert --uri %s %s", Quote(uri), binds))
+}
+
+func (adb *ADB) ContentUpdate(uri string, values map[string]interface{}, where string) error {
+	binds, err := contentBinds(values)
+	if err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("content update --uri %s %s", Quote(uri), binds)
+	if where != "" {
+		cmd += " --where " + Quote(where)
+	}
+	return adb.runInput(cmd)
+}
+
+func (adb *ADB) ContentDelete(uri string, where string) error {
+	cmd := fmt.Sprintf("content delete --uri %s", Quote(uri))
+	if where != "" {
+		cmd += " --where " + Quote(where)
+	}
+	return adb.runInput(cmd)
+}
@@ -0,0 +1,236 @@
+package adb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// BaselineDensity is the density at which 1dp equals 1px.
+const BaselineDensity = 160
+
+// Display holds the physical and override size and density of the default
+// display. Sizes are in the natural orientation, unset overrides are zero.
+type Display struct {
+	PhysicalSize    image.Point
+	OverrideSize    image.Point
+	PhysicalDensity int
+	OverrideDensity int
+}
+
+// Size returns the effective display size.
+func (d Display) Size() image.Point {
+	if d.OverrideSize != (image.Point{}) {
+		return d.OverrideSize
+	}
+	return d.PhysicalSize
+}
+
+// Density returns the effective display density.
+func (d Display) Density() int {
+	if d.OverrideDensity != 0 {
+		return d.OverrideDensity
+	}
+	return d.PhysicalDensity
+}
+
+var (
+	displaySizeRE    = regexp.MustCompile(`(Physical|Override) size: (\d+)x(\d+)`)
+	displayDensityRE = regexp.MustCompile(`(Physical|Override) density: (\d+)`)
+)
+
+func (adb *ADB) Display() (Display, error) {
+	var d Display
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("wm size; wm density", buf, nil); err != nil {
+		return d, err
+	}
+
+	out := buf.String()
+	for _, res := range displaySizeRE.FindAllStringSubmatch(out, -1) {
+		w, _ := strconv.Atoi(res[2])
+		h, _ := strconv.Atoi(res[3])
+		if res[1] == "Override" {
+			d.OverrideSize = image.Pt(w, h)
+			continue
+		}
+		d.PhysicalSize = image.Pt(w, h)
+	}
+	for _, res := range displayDensityRE.FindAllStringSubmatch(out, -1) {
+		v, _ := strconv.Atoi(res[2])
+		if res[1] == "Override" {
+			d.OverrideDensity = v
+			continue
+		}
+		d.PhysicalDensity = v
+	}
+
+	if d.Size() == (image.Point{}) || d.Density() == 0 {
+		return d, errors.New("could not determine display size and density")
+	}
+	return d, nil
+}
+
+// DisplaySize returns the effective display size in its natural orientation.
+func (adb *ADB) DisplaySize() (image.Point, error) {
+	d, err := adb.Display()
+	return d.Size(), err
+}
+
+// Density returns the effective display density, the value is cached
+// until SetDensity or ResetDensity is called.
+func (adb *ADB) Density() (int, error) {
+	if adb.dpi != 0 {
+		return adb.dpi, nil
+	}
+	d, err := adb.Display()
+	if err != nil {
+		return 0, err
+	}
+	adb.dpi = d.Density()
+	return adb.dpi, nil
+}
+
+func (adb *ADB) SetDisplaySize(w, h int) error {
+	return adb.runInputApply(fmt.Sprintf("wm size %dx%d", w, h), func() {
+		if adb.orient != nil {
+			adb.orient.Natural = image.Pt(w, h)
+		}
+	})
+}
+
+func (adb *ADB) ResetDisplaySize() error {
+	if err := adb.runInput("wm size reset"); err != nil {
+		return err
+	}
+	if adb.orient == nil {
+		return nil
+	}
+	size, err := adb.DisplaySize()
+	if err != nil {
+		return err
+	}
+	adb.orient.Natural = size
+	return nil
+}
+
+func (adb *ADB) SetDensity(dpi int) error {
+	adb.dpi = 0
+	return adb.runInput(fmt.Sprintf("wm density %d", dpi))
+}
+
+func (adb *ADB) ResetDensity() error {
+	adb.dpi = 0
+	return adb.runInput("wm density reset")
+}
+
+// DisplayOverride restores the display size and density overrides that
+// were active before OverrideDisplay was called.
+type DisplayOverride struct {
+	adb  *ADB
+	orig Display
+}
+
+// OverrideDisplay normalizes the display size and/or density, zero values
+// are left untouched.
+func (adb *ADB) OverrideDisplay(size image.Point, dpi int) (*DisplayOverride, error) {
+	orig, err := adb.Display()
+	if err != nil {
+		return nil, err
+	}
+	o := &DisplayOverride{adb: adb, orig: orig}
+	if size != (image.Point{}) {
+		if err := adb.SetDisplaySize(size.X, size.Y); err != nil {
+			return nil, err
+		}
+	}
+	if dpi != 0 {
+		if err := adb.SetDensity(dpi); err != nil {
+			_ = o.Revert()
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func (o *DisplayOverride) Revert() error {
+	var err error
+	if o.orig.OverrideSize == (image.Point{}) {
+		err = o.adb.ResetDisplaySize()
+	} else {
+		err = o.adb.SetDisplaySize(o.orig.OverrideSize.X, o.orig.OverrideSize.Y)
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.orig.OverrideDensity == 0 {
+		return o.adb.ResetDensity()
+	}
+	return o.adb.SetDensity(o.orig.OverrideDensity)
+}
+
+func (o *DisplayOverride) Close() error { return o.Revert() }
+
+// PX converts density independent pixels to pixels.
+func (adb *ADB) PX(dp float64) (int, error) {
+	dpi, err := adb.Density()
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(dp * float64(dpi) / BaselineDensity)), nil
+}
+
+// DP converts pixels to density independent pixels.
+func (adb *ADB) DP(px int) (float64, error) {
+	dpi, err := adb.Density()
+	if err != nil {
+		return 0, err
+	}
+	return float64(px) * BaselineDensity / float64(dpi), nil
+}
+
+func (adb *ADB) pxs(dps ...float64) ([]int, error) {
+	l := make([]int, len(dps))
+	for i, dp := range dps {
+		px, err := adb.PX(dp)
+		if err != nil {
+			return nil, err
+		}
+		l[i] = px
+	}
+	return l, nil
+}
+
+func (adb *ADB) TapDP(x, y float64) error {
+	p, err := adb.pxs(x, y)
+	if err != nil {
+		return err
+	}
+	return adb.Tap(p[0], p[1])
+}
+
+func (adb *ADB) TapQuickDP(x, y float64) error {
+	p, err := adb.pxs(x, y)
+	if err != nil {
+		return err
+	}
+	return adb.TapQuick(p[0], p[1])
+}
+
+func (adb *ADB) DragDP(x0, y0, x1, y1 float64, dur time.Duration) error {
+	p, err := adb.pxs(x0, y0, x1, y1)
+	if err != nil {
+		return err
+	}
+	return adb.Drag(p[0], p[1], p[2], p[3], dur)
+}
+
+func (adb *ADB) HoldDP(x, y float64, dur time.Duration) error {
+	return adb.DragDP(x, y, x, y, dur)
+}
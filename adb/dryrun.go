@@ -0,0 +1,67 @@
+package adb
+
+import (
+	"sync"
+	"time"
+)
+
+// DryRun records input commands (Tap, Drag, Text, AmStart, SetSetting, Batch, ...)
+// instead of executing them, all other commands (Screencap, Setting, ...)
+// still go through.
+//
+//	dry := adb.NewDryRun(func(c adb.Call) { log.Printf("dry-run %s: %s", c.Helper, c.Cmd) })
+//	client.Use(dry.Middleware())
+type DryRun struct {
+	rw    sync.Mutex
+	calls []Call
+	log   func(Call)
+	off   bool
+}
+
+// NewDryRun creates a DryRun, log is optional.
+func NewDryRun(log func(Call)) *DryRun {
+	return &DryRun{log: log}
+}
+
+// SetEnabled toggles dry-run at runtime, enabled by default.
+func (d *DryRun) SetEnabled(on bool) {
+	d.rw.Lock()
+	d.off = !on
+	d.rw.Unlock()
+}
+
+// Calls returns the recorded calls.
+func (d *DryRun) Calls() []Call {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	l := make([]Call, len(d.calls))
+	copy(l, d.calls)
+	return l
+}
+
+// Reset clears the recorded calls.
+func (d *DryRun) Reset() {
+	d.rw.Lock()
+	d.calls = d.calls[:0]
+	d.rw.Unlock()
+}
+
+func (d *DryRun) Middleware() Middleware {
+	return func(call *Call, next func() error) error {
+		d.rw.Lock()
+		off := d.off
+		d.rw.Unlock()
+		if !call.Input || off {
+			return next()
+		}
+
+		call.Start = time.Now()
+		d.rw.Lock()
+		d.calls = append(d.calls, *call)
+		d.rw.Unlock()
+		if d.log != nil {
+			d.log(*call)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package adb
+
+import (
+	"io"
+	"testing"
+)
+
+func TestDryRunDevice(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.SetPhysicalSize(108, 192)
+	var writes int
+	d.Handle("base64", func(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		writes++
+		return 0
+	})
+	c := fakeClient(t, d)
+	if err := c.SetOrientationAware(true); err != nil {
+		t.Fatal(err)
+	}
+
+	dry := NewDryRun(nil)
+	c.Use(dry.Middleware())
+
+	if err := c.RunScript(NewScript().Tap(10, 20)); err != nil {
+		t.Fatal(err)
+	}
+	if writes != 0 {
+		t.Errorf("script was pushed %d times in dry run mode", writes)
+	}
+
+	if err := c.SetRotation(Rotation90); err != nil {
+		t.Fatal(err)
+	}
+	if o, _ := c.Orientation(); o.Rotation != Rotation0 {
+		t.Errorf("orientation changed to %d in dry run mode", o.Rotation)
+	}
+	if err := c.SetDisplaySize(216, 384); err != nil {
+		t.Fatal(err)
+	}
+	if o, _ := c.Orientation(); o.Natural.X != 108 {
+		t.Errorf("natural size changed to %s in dry run mode", o.Natural)
+	}
+	if inputs := d.Inputs(); len(inputs) != 0 {
+		t.Errorf("expected no inputs, got %q", inputs)
+	}
+
+	dry.SetEnabled(false)
+	if err := c.SetRotation(Rotation90); err != nil {
+		t.Fatal(err)
+	}
+	if o, _ := c.Orientation(); o.Rotation != Rotation90 {
+		t.Errorf("expected rotation %d, got %d", Rotation90, o.Rotation)
+	}
+	if r, _ := c.Rotation(); r != Rotation90 {
+		t.Errorf("expected device rotation %d, got %d", Rotation90, r)
+	}
+}
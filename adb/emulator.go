@@ -0,0 +1,276 @@
+package adb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsoleAddr returns the console address of an emulator serial
+// (emulator-5554 => 127.0.0.1:5554).
+func ConsoleAddr(serial string) (string, error) {
+	const prefix = "emulator-"
+	if !strings.HasPrefix(serial, prefix) {
+		return "", fmt.Errorf("'%s' is not an emulator serial", serial)
+	}
+	port, err := strconv.Atoi(strings.TrimPrefix(serial, prefix))
+	if err != nil {
+		return "", fmt.Errorf("'%s' is not an emulator serial", serial)
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+}
+
+// ConsoleAuthToken reads ~/.emulator_console_auth_token.
+func ConsoleAuthToken() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	d, err := os.ReadFile(filepath.Join(home, ".emulator_console_auth_token"))
+	return strings.TrimSpace(string(d)), err
+}
+
+type ConsoleError struct {
+	Cmd string
+	Msg string
+}
+
+func (e *ConsoleError) Error() string { return fmt.Sprintf("console '%s': %s", e.Cmd, e.Msg) }
+
+// Console is an emulator console client. Not thread safe.
+type Console struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+// DialConsole connects to an emulator console and authenticates if token
+// is not empty.
+func DialConsole(addr, token string) (*Console, error) {
+	conn, err := net.DialTimeout("tcp", addr, time.Second*5)
+	if err != nil {
+		return nil, err
+	}
+	c := &Console{conn: conn, r: bufio.NewReader(conn), timeout: time.Second * 10}
+	if _, err := c.response("connect"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if token == "" {
+		return c, nil
+	}
+	if _, err := c.Command("auth " + token); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// DialEmulator connects to the console of an emulator serial using the
+// token in ~/.emulator_console_auth_token.
+func DialEmulator(serial string) (*Console, error) {
+	addr, err := ConsoleAddr(serial)
+	if err != nil {
+		return nil, err
+	}
+	token, err := ConsoleAuthToken()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return DialConsole(addr, token)
+}
+
+func (c *Console) response(cmd string) (string, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	lines := make([]string, 0)
+	for {
+		l, err := c.r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		l = strings.TrimRight(l, "\r\n")
+		switch {
+		case l == "OK":
+			return strings.Join(lines, "\n"), nil
+		case strings.HasPrefix(l, "KO"):
+			return "", &ConsoleError{Cmd: cmd, Msg: strings.TrimSpace(strings.TrimPrefix(l, "KO:"))}
+		}
+		lines = append(lines, l)
+	}
+}
+
+// Command sends a raw console command and returns its output.
+// Commands spanning multiple lines are rejected as every line would be
+// executed as a separate command.
+func (c *Console) Command(cmd string) (string, error) {
+	if strings.ContainsAny(cmd, "\r\n") {
+		return "", fmt.Errorf("console command contains a newline: %q", cmd)
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if _, err := fmt.Fprintf(c.conn, "%s\n", cmd); err != nil {
+		return "", err
+	}
+	return c.response(strings.SplitN(cmd, " ", 2)[0])
+}
+
+func (c *Console) run(format string, args ...interface{}) error {
+	_, err := c.Command(fmt.Sprintf(format, args...))
+	return err
+}
+
+func (c *Console) Close() error {
+	_, _ = fmt.Fprintf(c.conn, "quit\n")
+	return c.conn.Close()
+}
+
+func ftoa(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+// GeoFix sends a GPS fix, altitude in meters.
+func (c *Console) GeoFix(lon, lat, alt float64) error {
+	return c.run("geo fix %s %s %s", ftoa(lon), ftoa(lat), ftoa(alt))
+}
+
+func (c *Console) SMSSend(from, text string) error {
+	return c.run("sms send %s %s", from, text)
+}
+
+// GSMCall simulates an incoming call.
+func (c *Console) GSMCall(number string) error   { return c.run("gsm call %s", number) }
+func (c *Console) GSMAccept(number string) error { return c.run("gsm accept %s", number) }
+func (c *Console) GSMCancel(number string) error { return c.run("gsm cancel %s", number) }
+
+func (c *Console) PowerCapacity(percent int) error { return c.run("power capacity %d", percent) }
+
+func (c *Console) PowerAC(online bool) error {
+	v := "off"
+	if online {
+		v = "on"
+	}
+	return c.run("power ac %s", v)
+}
+
+// PowerStatus sets the battery status: unknown, charging, discharging,
+// not-charging or full.
+func (c *Console) PowerStatus(status string) error { return c.run("power status %s", status) }
+
+// NetworkSpeed sets the network speed, e.g. gsm, edge, umts, lte, full or
+// <up>:<down> in kbps.
+func (c *Console) NetworkSpeed(speed string) error { return c.run("network speed %s", speed) }
+
+// NetworkDelay sets the network latency, e.g. gprs, edge, umts, none or
+// <min>:<max> in ms.
+func (c *Console) NetworkDelay(delay string) error { return c.run("network delay %s", delay) }
+
+// Rotate rotates the emulator 90 degrees counter clockwise.
+func (c *Console) Rotate() error { return c.run("rotate") }
+
+// FakeConsole is a local stand-in for an emulator console that records
+// the commands it receives, for testing code that uses Console.
+type FakeConsole struct {
+	l     net.Listener
+	token string
+
+	rw      sync.Mutex
+	cmds    []string
+	respond func(cmd string) (string, error)
+}
+
+// NewFakeConsole listens on a random local port, token is optional.
+func NewFakeConsole(token string) (*FakeConsole, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	f := &FakeConsole{l: l, token: token}
+	go f.serve()
+	return f, nil
+}
+
+func (f *FakeConsole) Addr() string { return f.l.Addr().String() }
+
+// Commands returns the received commands, excluding auth and quit.
+func (f *FakeConsole) Commands() []string {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	return append([]string{}, f.cmds...)
+}
+
+// SetRespond sets the function generating the output for a command, a
+// non-nil error is sent as KO. Commands reply OK without output if nil.
+func (f *FakeConsole) SetRespond(respond func(cmd string) (string, error)) {
+	f.rw.Lock()
+	f.respond = respond
+	f.rw.Unlock()
+}
+
+func (f *FakeConsole) Close() error { return f.l.Close() }
+
+func (f *FakeConsole) serve() {
+	for {
+		conn, err := f.l.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *FakeConsole) handle(conn net.Conn) {
+	defer conn.Close()
+	w := bufio.NewWriter(conn)
+	reply := func(out string, err error) {
+		if out != "" {
+			fmt.Fprintf(w, "%s\r\n", strings.ReplaceAll(out, "\n", "\r\n"))
+		}
+		if err != nil {
+			fmt.Fprintf(w, "KO: %s\r\n", err)
+		} else {
+			fmt.Fprint(w, "OK\r\n")
+		}
+		w.Flush()
+	}
+
+	authed := f.token == ""
+	banner := "Android Console: type 'help' for a list of commands"
+	if !authed {
+		banner = "Android Console: Authentication required"
+	}
+	reply(banner, nil)
+
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		cmd := strings.TrimSpace(s.Text())
+		switch {
+		case cmd == "quit" || cmd == "exit":
+			return
+		case strings.HasPrefix(cmd, "auth "):
+			if strings.TrimPrefix(cmd, "auth ") != f.token {
+				reply("", errors.New("authentication token does not match"))
+				continue
+			}
+			authed = true
+			reply("", nil)
+			continue
+		case !authed:
+			reply("", errors.New("authentication required"))
+			continue
+		}
+
+		f.rw.Lock()
+		f.cmds = append(f.cmds, cmd)
+		respond := f.respond
+		f.rw.Unlock()
+		if respond == nil {
+			reply("", nil)
+			continue
+		}
+		reply(respond(cmd))
+	}
+}
@@ -0,0 +1,146 @@
+package adb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func fakeConsole(t *testing.T, token string) *FakeConsole {
+	t.Helper()
+	f, err := NewFakeConsole(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestDialConsole(t *testing.T) {
+	f := fakeConsole(t, "")
+	c, err := DialConsole(f.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.GeoFix(4.4, 51.2, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PowerAC(false); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"geo fix 4.4 51.2 10", "power ac off"}
+	if cmds := f.Commands(); !reflect.DeepEqual(cmds, exp) {
+		t.Errorf("expected commands %q, got %q", exp, cmds)
+	}
+}
+
+func TestDialConsoleAuth(t *testing.T) {
+	f := fakeConsole(t, "secret")
+
+	c, err := DialConsole(f.Addr(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Rotate(); err != nil {
+		t.Errorf("authenticated command failed: %s", err)
+	}
+	c.Close()
+
+	_, err = DialConsole(f.Addr(), "wrong")
+	var ce *ConsoleError
+	if !errors.As(err, &ce) || ce.Cmd != "auth" {
+		t.Errorf("expected auth ConsoleError, got %v", err)
+	}
+
+	c, err = DialConsole(f.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Rotate(); !errors.As(err, &ce) {
+		t.Errorf("expected ConsoleError without auth, got %v", err)
+	}
+
+	exp := []string{"rotate"}
+	if cmds := f.Commands(); !reflect.DeepEqual(cmds, exp) {
+		t.Errorf("expected commands %q, got %q", exp, cmds)
+	}
+}
+
+func TestConsoleKO(t *testing.T) {
+	f := fakeConsole(t, "")
+	f.SetRespond(func(cmd string) (string, error) {
+		return "", errors.New("bad parameters")
+	})
+	c, err := DialConsole(f.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.NetworkSpeed("warp")
+	var ce *ConsoleError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConsoleError, got %v", err)
+	}
+	if ce.Cmd != "network" || ce.Msg != "bad parameters" {
+		t.Errorf("unexpected ConsoleError %+v", ce)
+	}
+}
+
+func TestConsoleMultiline(t *testing.T) {
+	f := fakeConsole(t, "")
+	f.SetRespond(func(cmd string) (string, error) {
+		if cmd != "help" {
+			return "", nil
+		}
+		return "geo\npower\nrotate", nil
+	})
+	c, err := DialConsole(f.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	out, err := c.Command("help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "geo\npower\nrotate"; out != exp {
+		t.Errorf("expected %q, got %q", exp, out)
+	}
+
+	// the connection must still be in sync after multi-line output
+	if out, err = c.Command("rotate"); err != nil || out != "" {
+		t.Errorf("expected empty output, got %q, %v", out, err)
+	}
+}
+
+func TestConsoleNewline(t *testing.T) {
+	f := fakeConsole(t, "")
+	c, err := DialConsole(f.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.SMSSend("5551234", "hi\nkill"); err == nil {
+		t.Error("expected error for sms with a newline")
+	}
+	if err := c.GSMCall("5551234\r\nkill"); err == nil {
+		t.Error("expected error for number with a newline")
+	}
+	if err := c.NetworkSpeed("full\nkill"); err == nil {
+		t.Error("expected error for speed with a newline")
+	}
+	if err := c.SMSSend("5551234", "hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"sms send 5551234 hi"}
+	if cmds := f.Commands(); !reflect.DeepEqual(cmds, exp) {
+		t.Errorf("expected commands %q, got %q", exp, cmds)
+	}
+}
@@ -0,0 +1,149 @@
+package adb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrDeviceNotFound   = errors.New("device not found")
+	ErrUnauthorized     = errors.New("device unauthorized")
+	ErrOffline          = errors.New("device offline")
+	ErrTransportClosed  = errors.New("transport closed")
+	ErrProtocol         = errors.New("shell protocol error")
+	ErrCommandNotFound  = errors.New("command not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrTimeout          = errors.New("timeout")
+	// ErrBusy is returned by commands issued while ScreencapContinuous is
+	// active on the same client.
+	ErrBusy = errors.New("client busy with continuous screencap")
+)
+
+// Error is a classified error, errors.Is(err, ErrOffline) etc. match its
+// Kind while errors.Is/As on the underlying error keep working through
+// Unwrap.
+type Error struct {
+	Kind error
+	// Output is the adb or shell output the error was classified from.
+	Output string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Err != nil && e.Err != e.Kind {
+		msg = fmt.Sprintf("%s: %s", msg, e.Err)
+	}
+	if e.Output != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Output)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error        { return e.Err }
+func (e *Error) Is(target error) bool { return target == e.Kind }
+
+// classifiers are matched against lowercased output in order, more specific
+// kinds first.
+var classifiers = []struct {
+	kind    error
+	pattern string
+}{
+	{ErrUnauthorized, `\bdevice (?:'[^']*' )?unauthorized\b|^\S+\s+unauthorized$`},
+	{ErrPermissionDenied, `\bpermission denied\b|\boperation not permitted\b|\bsecurityexception\b`},
+	{ErrCommandNotFound, `: (?:inaccessible or )?not found$|\bcommand not found\b|\bcan't find service\b`},
+	{ErrOffline, `\bdevice (?:'[^']*' )?offline\b|\bdevice still connecting\b`},
+	{ErrDeviceNotFound, `\bdevice (?:'[^']*' )?not found\b|\bnot found: device\b|\bno devices/emulators found\b`},
+	{ErrTransportClosed, `^(?:error: )?closed$|\bprotocol fault\b|\bconnection reset\b|\bbroken pipe\b|\bcannot connect to daemon\b`},
+}
+
+var classifierREs = compileClassifiers()
+
+func compileClassifiers() []*regexp.Regexp {
+	l := make([]*regexp.Regexp, len(classifiers))
+	for i, c := range classifiers {
+		l[i] = regexp.MustCompile(`(?m)(?:` + c.pattern + `)`)
+	}
+	return l
+}
+
+// classify returns the sentinel matching adb or shell output, nil if
+// nothing matched.
+func classify(output string) error {
+	o := strings.ToLower(strings.ReplaceAll(output, "\r", ""))
+	for i, re := range classifierREs {
+		if re.MatchString(o) {
+			return classifiers[i].kind
+		}
+	}
+	return nil
+}
+
+// transportError classifies an error that broke the shell session.
+func transportError(err error, output string) error {
+	output = strings.TrimSpace(output)
+	kind := classify(output)
+	switch kind {
+	case ErrDeviceNotFound, ErrUnauthorized, ErrOffline, ErrTransportClosed:
+	default:
+		kind = ErrTransportClosed
+	}
+	return &Error{Kind: kind, Output: output, Err: err}
+}
+
+// exitKind classifies a non-zero exit code.
+func exitKind(code int, stderr string) error {
+	switch code {
+	case 126:
+		return ErrPermissionDenied
+	case 127:
+		return ErrCommandNotFound
+	}
+	switch kind := classify(stderr); kind {
+	case ErrPermissionDenied, ErrCommandNotFound:
+		return kind
+	}
+	return nil
+}
+
+type RecoveryAction int
+
+const (
+	// RecoveryNone: there is no error to recover from.
+	RecoveryNone RecoveryAction = iota
+	// RecoveryAbort: retrying won't help (e.g. unauthorized, command not
+	// found).
+	RecoveryAbort
+	// RecoveryRetry: retry the command.
+	RecoveryRetry
+	// RecoveryReconnect: reconnect the device (e.g. adb connect) before
+	// retrying.
+	RecoveryReconnect
+)
+
+// Recovery suggests how to handle err.
+func Recovery(err error) RecoveryAction {
+	switch {
+	case err == nil:
+		return RecoveryNone
+	case errors.Is(err, ErrUnauthorized),
+		errors.Is(err, ErrBusy),
+		errors.Is(err, ErrCommandNotFound),
+		errors.Is(err, ErrPermissionDenied):
+		return RecoveryAbort
+	case errors.Is(err, ErrDeviceNotFound),
+		errors.Is(err, ErrOffline):
+		return RecoveryReconnect
+	case errors.Is(err, ErrTransportClosed),
+		errors.Is(err, ErrProtocol),
+		errors.Is(err, ErrTimeout):
+		return RecoveryRetry
+	}
+	var ce *CmdError
+	if errors.As(err, &ce) {
+		return RecoveryAbort
+	}
+	return RecoveryRetry
+}
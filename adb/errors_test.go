@@ -0,0 +1,76 @@
+package adb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		output string
+		kind   error
+	}{
+		{"error: device unauthorized.\nThis adb server's $ADB_VENDOR_KEYS is not set\nTry 'adb kill-server' if that seems wrong.", ErrUnauthorized},
+		{"error: device 'emulator-5554' unauthorized", ErrUnauthorized},
+		{"List of devices attached\nR58M123ABC\tunauthorized", ErrUnauthorized},
+		{"error: device offline", ErrOffline},
+		{"adb: device 'R58M123ABC' offline", ErrOffline},
+		{"error: device still connecting", ErrOffline},
+		{"error: device 'emulator-5556' not found", ErrDeviceNotFound},
+		{"adb: error: failed to get feature set: no devices/emulators found", ErrDeviceNotFound},
+		{"error: closed", ErrTransportClosed},
+		{"error: closed\r\n", ErrTransportClosed},
+		{"error: protocol fault (couldn't read status): Connection reset by peer", ErrTransportClosed},
+		{"adb: error: failed to read copy response: broken pipe", ErrTransportClosed},
+		{"* cannot connect to daemon at tcp:5037: Connection refused", ErrTransportClosed},
+		{"/system/bin/sh: frobnicate: inaccessible or not found", ErrCommandNotFound},
+		{"/system/bin/sh: frobnicate: not found", ErrCommandNotFound},
+		{"cmd: Can't find service: bluetooth_manager", ErrCommandNotFound},
+		{"ls: /data/data: Permission denied", ErrPermissionDenied},
+		{"su: setgid failed: Operation not permitted", ErrPermissionDenied},
+		{
+			"Exception occurred while executing 'put':\n" +
+				"java.lang.SecurityException: Permission denial: writing to settings requires:android.permission.WRITE_SECURE_SETTINGS",
+			ErrPermissionDenied,
+		},
+		// permission denied wins over the transport looking words
+		{"run-as: couldn't stat /data/user/0/com.example: Permission denied\nerror: closed", ErrPermissionDenied},
+
+		// too broad before
+		{"Error: Activity not started, unable to resolve Intent", nil},
+		{"Warning: Activity not started, its current task has been brought to the front", nil},
+		{"cp: /sdcard/x: file closed unexpectedly", nil},
+		{"Installation not allowed by user", nil},
+		{"Error type 3\nError: Activity class {com.example/com.example.Main} does not exist.", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if kind := classify(test.output); kind != test.kind {
+			t.Errorf("%q: expected %v, got %v", test.output, test.kind, kind)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		err    error
+		action RecoveryAction
+	}{
+		{nil, RecoveryNone},
+		{&Error{Kind: ErrUnauthorized}, RecoveryAbort},
+		{&Error{Kind: ErrBusy}, RecoveryAbort},
+		{&CmdError{ExitCode: 127, Kind: ErrCommandNotFound}, RecoveryAbort},
+		{&CmdError{ExitCode: 1}, RecoveryAbort},
+		{&Error{Kind: ErrOffline}, RecoveryReconnect},
+		{fmt.Errorf("wrapped: %w", &Error{Kind: ErrDeviceNotFound}), RecoveryReconnect},
+		{&Error{Kind: ErrTransportClosed}, RecoveryRetry},
+		{ErrTimeout, RecoveryRetry},
+		{errors.New("unknown"), RecoveryRetry},
+	}
+	for _, test := range tests {
+		if action := Recovery(test.err); action != test.action {
+			t.Errorf("%v: expected %d, got %d", test.err, test.action, action)
+		}
+	}
+}
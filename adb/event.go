@@ -2,20 +2,27 @@ package adb
 
 import (
 	"fmt"
-	"strings"
+	"image"
 	"time"
 )
 
 func (adb *ADB) Tap(x, y int) error {
-	return adb.Run(fmt.Sprintf("input tap %d %d >/dev/null 2>&1", x, y), nil, nil)
+	p := image.Pt(x, y)
+	x, y = adb.xy(x, y)
+	return adb.runInput(fmt.Sprintf("input tap %d %d >/dev/null 2>&1", x, y), p)
 }
 
 func (adb *ADB) TapQuick(x, y int) error {
-	return adb.Run(fmt.Sprintf("input tap %d %d >/dev/null 2>&1 &", x, y), nil, nil)
+	p := image.Pt(x, y)
+	x, y = adb.xy(x, y)
+	return adb.runInput(fmt.Sprintf("input tap %d %d >/dev/null 2>&1 &", x, y), p)
 }
 
 func (adb *ADB) Drag(x0, y0, x1, y1 int, dur time.Duration) error {
-	return adb.Run(
+	p0, p1 := image.Pt(x0, y0), image.Pt(x1, y1)
+	x0, y0 = adb.xy(x0, y0)
+	x1, y1 = adb.xy(x1, y1)
+	return adb.runInput(
 		fmt.Sprintf(
 			"input swipe %d %d %d %d %d >/dev/null 2>&1",
 			x0,
@@ -24,8 +31,8 @@ func (adb *ADB) Drag(x0, y0, x1, y1 int, dur time.Duration) error {
 			y1,
 			dur.Milliseconds(),
 		),
-		nil,
-		nil,
+		p0,
+		p1,
 	)
 }
 
@@ -34,6 +41,9 @@ func (adb *ADB) Hold(x, y int, dur time.Duration) error {
 }
 
 func (adb *ADB) Text(s string) error {
-	s = strings.ReplaceAll(s, "'", "'\"'\"'")
-	return adb.Run(fmt.Sprintf("input text '%s' > /dev/null 2>&1", s), nil, nil)
+	return adb.runInput(fmt.Sprintf("input text %s > /dev/null 2>&1", Quote(s)))
+}
+
+func (adb *ADB) KeyEvent(keycode string) error {
+	return adb.runInput(fmt.Sprintf("input keyevent %s >/dev/null 2>&1", Quote(keycode)))
 }
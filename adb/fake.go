@@ -0,0 +1,911 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scene provides the frames served by a FakeDevice's screencap.
+type Scene interface {
+	// Frame returns the current screen contents.
+	Frame() image.Image
+	// Input is called for every input command, args excluding "input".
+	Input(args []string)
+}
+
+// FrameScene serves a fixed sequence of frames, advancing one frame per
+// screencap and repeating the last one.
+type FrameScene struct {
+	rw     sync.Mutex
+	frames []image.Image
+	n      int
+}
+
+func NewFrameScene(frames ...image.Image) *FrameScene {
+	return &FrameScene{frames: frames}
+}
+
+// LoadFrameScene creates a FrameScene from png files.
+func LoadFrameScene(paths ...string) (*FrameScene, error) {
+	frames := make([]image.Image, 0, len(paths))
+	for _, p := range paths {
+		img, err := loadPNG(p)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, img)
+	}
+	return NewFrameScene(frames...), nil
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func (s *FrameScene) Frame() image.Image {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	if len(s.frames) == 0 {
+		return nil
+	}
+	i := s.n
+	if i >= len(s.frames) {
+		i = len(s.frames) - 1
+	}
+	s.n++
+	return s.frames[i]
+}
+
+func (s *FrameScene) Input(args []string) {}
+
+// SceneNode is a single screen in a SceneGraph.
+type SceneNode struct {
+	Name  string
+	Image image.Image
+	Edges []SceneEdge
+}
+
+// SceneEdge moves a SceneGraph to the node named Next when a tap lands
+// in Tap or when keyevent Key is sent.
+type SceneEdge struct {
+	Tap  image.Rectangle
+	Key  string
+	Next string
+}
+
+// SceneGraph is a scripted scene whose screen changes in response to
+// input.
+type SceneGraph struct {
+	rw    sync.Mutex
+	nodes map[string]*SceneNode
+	cur   string
+}
+
+// NewSceneGraph creates a SceneGraph starting at the node named start.
+func NewSceneGraph(start string, nodes ...*SceneNode) *SceneGraph {
+	g := &SceneGraph{nodes: make(map[string]*SceneNode, len(nodes)), cur: start}
+	for _, n := range nodes {
+		g.nodes[n.Name] = n
+	}
+	return g
+}
+
+// Current returns the name of the current node.
+func (g *SceneGraph) Current() string {
+	g.rw.Lock()
+	defer g.rw.Unlock()
+	return g.cur
+}
+
+// Goto sets the current node.
+func (g *SceneGraph) Goto(name string) {
+	g.rw.Lock()
+	g.cur = name
+	g.rw.Unlock()
+}
+
+func (g *SceneGraph) Frame() image.Image {
+	g.rw.Lock()
+	defer g.rw.Unlock()
+	n, ok := g.nodes[g.cur]
+	if !ok {
+		return nil
+	}
+	return n.Image
+}
+
+func (g *SceneGraph) Input(args []string) {
+	if len(args) < 2 {
+		return
+	}
+
+	g.rw.Lock()
+	defer g.rw.Unlock()
+	n, ok := g.nodes[g.cur]
+	if !ok {
+		return
+	}
+
+	var tap *image.Point
+	switch args[0] {
+	case "tap", "swipe":
+		if len(args) < 3 {
+			return
+		}
+		x, errx := strconv.Atoi(args[1])
+		y, erry := strconv.Atoi(args[2])
+		if errx != nil || erry != nil {
+			return
+		}
+		if args[0] == "swipe" && (len(args) < 5 || args[1] != args[3] || args[2] != args[4]) {
+			return
+		}
+		tap = &image.Point{x, y}
+	case "keyevent":
+	default:
+		return
+	}
+
+	for _, e := range n.Edges {
+		if tap != nil && tap.In(e.Tap) || tap == nil && e.Key != "" && e.Key == args[1] {
+			g.cur = e.Next
+			return
+		}
+	}
+}
+
+// FakeHandler implements a custom command for a FakeDevice and returns its
+// exit code.
+type FakeHandler func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// FakeDevice is an in-process stand-in for an android device that speaks
+// the shell protocol expected by ADB. It serves screencap frames from a
+// Scene, keeps settings and the top activity in memory and records all
+// input commands.
+type FakeDevice struct {
+	serial string
+	scene  Scene
+
+	rw       sync.Mutex
+	settings map[Namespace]map[string]string
+	top      string
+	inputs   []string
+	physical image.Point
+	size     image.Point
+	dpi      int
+	rotation Rotation
+	handlers map[string]FakeHandler
+}
+
+const fakeDensity = 420
+
+// FakeLauncher is the top activity of a FakeDevice when no app is running.
+const FakeLauncher = "com.android.launcher3"
+
+func NewFakeDevice(serial string, scene Scene) *FakeDevice {
+	return &FakeDevice{
+		serial:   serial,
+		scene:    scene,
+		settings: make(map[Namespace]map[string]string),
+		top:      FakeLauncher,
+		physical: image.Point{1080, 1920},
+		handlers: make(map[string]FakeHandler),
+	}
+}
+
+// Client returns an uninitialized client connected to this device.
+func (d *FakeDevice) Client(maxBuffer int) *ADB {
+	c := New("", d.serial, maxBuffer)
+	c.spawn = d.spawn
+	return c
+}
+
+// Handle registers a custom command, overriding any builtin one.
+func (d *FakeDevice) Handle(name string, h FakeHandler) {
+	d.rw.Lock()
+	d.handlers[name] = h
+	d.rw.Unlock()
+}
+
+// SetPhysicalSize sets the size reported by wm size, it should match the
+// frames served by the scene (default 1080x1920).
+func (d *FakeDevice) SetPhysicalSize(w, h int) {
+	d.rw.Lock()
+	d.physical = image.Point{w, h}
+	d.rw.Unlock()
+}
+
+// SetRotation sets the rotation reported by dumpsys input, putting the
+// system user_rotation setting changes it as well.
+func (d *FakeDevice) SetRotation(r Rotation) {
+	d.rw.Lock()
+	d.rotation = r.norm()
+	d.rw.Unlock()
+}
+
+// Inputs returns all recorded input commands, e.g. "input tap 10 20".
+func (d *FakeDevice) Inputs() []string {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	return append([]string{}, d.inputs...)
+}
+
+func (d *FakeDevice) ResetInputs() {
+	d.rw.Lock()
+	d.inputs = nil
+	d.rw.Unlock()
+}
+
+// TopActivity returns the package currently in the foreground.
+func (d *FakeDevice) TopActivity() string {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	return d.top
+}
+
+func (d *FakeDevice) SetTopActivity(pkg string) {
+	d.rw.Lock()
+	d.top = pkg
+	d.rw.Unlock()
+}
+
+// Setting returns a setting value and whether it exists.
+func (d *FakeDevice) Setting(namespace Namespace, key string) (string, bool) {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	v, ok := d.settings[namespace][key]
+	return v, ok
+}
+
+func (d *FakeDevice) SetSetting(namespace Namespace, key, value string) {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	d.setSetting(namespace, key, value)
+}
+
+func (d *FakeDevice) setSetting(namespace Namespace, key, value string) {
+	if d.settings[namespace] == nil {
+		d.settings[namespace] = make(map[string]string)
+	}
+	d.settings[namespace][key] = value
+}
+
+func (d *FakeDevice) frame() image.Image {
+	if d.scene == nil {
+		return nil
+	}
+	return d.scene.Frame()
+}
+
+func (d *FakeDevice) spawn(args []string) (*shell, error) {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	errr, errw := io.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		sh := &fakeShell{dev: d, stdout: outw, stderr: errw}
+		if len(args) != 0 && args[0] != "su" {
+			// adb shell <command>: run it and exit
+			sh.line(strings.Join(args, " "))
+			outw.CloseWithError(io.EOF)
+			errw.CloseWithError(io.EOF)
+			return
+		}
+		r := bufio.NewReader(inr)
+		for {
+			l, err := r.ReadString('\n')
+			if l = strings.TrimRight(l, "\n"); l != "" {
+				sh.line(l)
+			}
+			if err != nil {
+				outw.CloseWithError(io.EOF)
+				errw.CloseWithError(io.EOF)
+				return
+			}
+		}
+	}()
+
+	kill := func() error {
+		err := errors.New("killed")
+		inr.CloseWithError(err)
+		outw.CloseWithError(err)
+		errw.CloseWithError(err)
+		return nil
+	}
+
+	return &shell{
+		stdin:  inw,
+		stdout: outr,
+		stderr: errr,
+		wait:   func() error { <-done; return nil },
+		kill:   kill,
+	}, nil
+}
+
+// fakeShell interprets the small subset of sh used by this package.
+type fakeShell struct {
+	dev    *FakeDevice
+	stdout io.Writer
+	stderr io.Writer
+	status int
+}
+
+type fakeCmd struct {
+	args       []string
+	stdout     string // "", "null" or "stderr"
+	stderr     string // "", "null" or "stdout"
+	background bool
+}
+
+func (sh *fakeShell) line(l string) {
+	list, err := parseFakeLine(l, sh.status)
+	if err != nil {
+		fmt.Fprintf(sh.stderr, "sh: %s\n", err)
+		sh.status = 2
+		return
+	}
+
+	for _, pipeline := range list {
+		var in []byte
+		for i, c := range pipeline {
+			out := bytes.NewBuffer(nil)
+			errw := bytes.NewBuffer(nil)
+			sh.status = sh.dev.exec(c.args, bytes.NewReader(in), out, errw)
+			in = out.Bytes()
+
+			var stdout, stderr io.Writer = sh.stdout, sh.stderr
+			if i != len(pipeline)-1 {
+				stdout = io.Discard
+			}
+			switch c.stdout {
+			case "null":
+				stdout = io.Discard
+			case "stderr":
+				stdout = sh.stderr
+			}
+			switch c.stderr {
+			case "null":
+				stderr = io.Discard
+			case "stdout":
+				stderr = stdout
+			}
+			if c.stderr == "stdout" && i != len(pipeline)-1 {
+				in = append(in, errw.Bytes()...)
+			}
+			if c.background {
+				sh.status = 0
+			}
+
+			// zero length writes to a pipe block until read
+			if errw.Len() != 0 {
+				_, _ = stderr.Write(errw.Bytes())
+			}
+			if i == len(pipeline)-1 && out.Len() != 0 {
+				_, _ = stdout.Write(out.Bytes())
+			}
+		}
+	}
+}
+
+// parseFakeLine splits a line into a list of pipelines.
+func parseFakeLine(l string, status int) ([][]fakeCmd, error) {
+	type token struct {
+		s  string
+		op bool
+	}
+	toks := make([]token, 0)
+	var cur strings.Builder
+	var has bool
+	flush := func() {
+		if has {
+			toks = append(toks, token{s: cur.String()})
+		}
+		cur.Reset()
+		has = false
+	}
+
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case c == '\'':
+			j := strings.IndexByte(l[i+1:], '\'')
+			if j < 0 {
+				return nil, errors.New("unterminated quoted string")
+			}
+			cur.WriteString(l[i+1 : i+1+j])
+			has = true
+			i += j + 1
+		case c == '"':
+			j := strings.IndexByte(l[i+1:], '"')
+			if j < 0 {
+				return nil, errors.New("unterminated quoted string")
+			}
+			cur.WriteString(strings.ReplaceAll(l[i+1:i+1+j], "$?", strconv.Itoa(status)))
+			has = true
+			i += j + 1
+		case c == '\\' && i+1 < len(l):
+			cur.WriteByte(l[i+1])
+			has = true
+			i++
+		case c == '$' && i+1 < len(l) && l[i+1] == '?':
+			cur.WriteString(strconv.Itoa(status))
+			has = true
+			i++
+		case c == ' ' || c == '\t':
+			flush()
+		case c == ';' || c == '|' || c == '&' || c == '<' || c == '>':
+			if c == '>' && has && cur.String() == "2" {
+				cur.Reset()
+				has = false
+				toks = append(toks, token{s: "2>", op: true})
+				if strings.HasPrefix(l[i+1:], "&1") {
+					toks[len(toks)-1].s = "2>&1"
+					i += 2
+				}
+				continue
+			}
+			flush()
+			op := string(c)
+			switch {
+			case c == '&' && strings.HasPrefix(l[i+1:], "&"):
+				op = "&&"
+				i++
+			case c == '|' && strings.HasPrefix(l[i+1:], "|"):
+				op = "||"
+				i++
+			case c == '>' && strings.HasPrefix(l[i+1:], "&2"):
+				op = ">&2"
+				i += 2
+			case c == '>' && strings.HasPrefix(l[i+1:], ">"):
+				i++
+			}
+			toks = append(toks, token{s: op, op: true})
+		default:
+			cur.WriteByte(c)
+			has = true
+		}
+	}
+	flush()
+
+	list := make([][]fakeCmd, 0, 1)
+	pipeline := make([]fakeCmd, 0, 1)
+	cmd := fakeCmd{}
+	endCmd := func() {
+		if len(cmd.args) != 0 {
+			pipeline = append(pipeline, cmd)
+		}
+		cmd = fakeCmd{}
+	}
+	endPipeline := func() {
+		endCmd()
+		if len(pipeline) != 0 {
+			list = append(list, pipeline)
+		}
+		pipeline = make([]fakeCmd, 0, 1)
+	}
+
+	target := func(i int) (string, error) {
+		if i >= len(toks) || toks[i].op {
+			return "", errors.New("missing redirect target")
+		}
+		if toks[i].s == "/dev/null" {
+			return "null", nil
+		}
+		return "", fmt.Errorf("cannot redirect to '%s'", toks[i].s)
+	}
+
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if !t.op {
+			cmd.args = append(cmd.args, t.s)
+			continue
+		}
+		var err error
+		switch t.s {
+		case "|":
+			endCmd()
+		case ";", "&&", "||":
+			endPipeline()
+		case "&":
+			cmd.background = true
+			endPipeline()
+		case ">":
+			cmd.stdout, err = target(i + 1)
+			i++
+		case "2>":
+			cmd.stderr, err = target(i + 1)
+			i++
+		case "2>&1":
+			cmd.stderr = "stdout"
+		case ">&2":
+			cmd.stdout = "stderr"
+		case "<":
+			i++
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	endPipeline()
+
+	return list, nil
+}
+
+func (d *FakeDevice) exec(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	d.rw.Lock()
+	h, ok := d.handlers[args[0]]
+	d.rw.Unlock()
+	if ok {
+		return h(args, stdin, stdout, stderr)
+	}
+
+	switch args[0] {
+	case "true", "rm", "mkdir":
+		return 0
+	case "false":
+		return 1
+	case "echo":
+		return fakeEcho(args[1:], stdout)
+	case "printf":
+		return fakePrintf(args[1:], stdout)
+	case "sleep":
+		if len(args) > 1 {
+			if f, err := strconv.ParseFloat(args[1], 64); err == nil {
+				time.Sleep(time.Duration(f * float64(time.Second)))
+			}
+		}
+		return 0
+	case "id":
+		fmt.Fprintln(stdout, "2000")
+		return 0
+	case "grep":
+		return fakeGrep(args[1:], stdin, stdout, stderr)
+	case "head":
+		return fakeHead(args[1:], stdin, stdout)
+	case "screencap":
+		return d.screencap(args[1:], stdout, stderr)
+	case "input":
+		return d.input(args[1:], stderr)
+	case "settings":
+		return d.settingsCmd(args[1:], stdout, stderr)
+	case "am":
+		return d.am(args[1:], stderr)
+	case "dumpsys":
+		return d.dumpsys(args[1:], stdout)
+	case "wm":
+		return d.wm(args[1:], stdout, stderr)
+	}
+
+	fmt.Fprintf(stderr, "/system/bin/sh: %s: inaccessible or not found\n", args[0])
+	return 127
+}
+
+func fakeEcho(args []string, stdout io.Writer) int {
+	var n, e bool
+	for len(args) != 0 && strings.HasPrefix(args[0], "-") && strings.Trim(args[0][1:], "ne") == "" {
+		n = n || strings.Contains(args[0], "n")
+		e = e || strings.Contains(args[0], "e")
+		args = args[1:]
+	}
+	s := strings.Join(args, " ")
+	if e {
+		s = fakeUnescape(s)
+	}
+	if !n {
+		s += "\n"
+	}
+	_, _ = io.WriteString(stdout, s)
+	return 0
+}
+
+func fakeUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '\\':
+			b.WriteByte('\\')
+		case 'x':
+			if i+2 < len(s) {
+				if v, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					b.WriteByte(byte(v))
+					i += 2
+					continue
+				}
+			}
+			b.WriteString("\\x")
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func fakePrintf(args []string, stdout io.Writer) int {
+	if len(args) == 0 {
+		return 1
+	}
+	vals := make([]interface{}, len(args)-1)
+	for i, a := range args[1:] {
+		vals[i] = a
+		if n, err := strconv.Atoi(a); err == nil {
+			vals[i] = n
+		}
+	}
+	fmt.Fprintf(stdout, fakeUnescape(args[0]), vals...)
+	return 0
+}
+
+func fakeGrep(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var invert, fixed bool
+	var pattern string
+	for _, a := range args {
+		switch a {
+		case "-v":
+			invert = true
+		case "-F":
+			fixed = true
+		case "-E":
+		default:
+			pattern = a
+		}
+	}
+	if fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Fprintf(stderr, "grep: %s\n", err)
+		return 2
+	}
+
+	exit := 1
+	s := bufio.NewScanner(stdin)
+	for s.Scan() {
+		if re.MatchString(s.Text()) != invert {
+			fmt.Fprintln(stdout, s.Text())
+			exit = 0
+		}
+	}
+	return exit
+}
+
+func fakeHead(args []string, stdin io.Reader, stdout io.Writer) int {
+	n := 10
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-n" {
+			n, _ = strconv.Atoi(args[i+1])
+		}
+	}
+	s := bufio.NewScanner(stdin)
+	for i := 0; i < n && s.Scan(); i++ {
+		fmt.Fprintln(stdout, s.Text())
+	}
+	return 0
+}
+
+// screencap writes the current frame in the raw screencap format or as png
+// with -p.
+func (d *FakeDevice) screencap(args []string, stdout, stderr io.Writer) int {
+	frame := d.frame()
+	if frame == nil {
+		fmt.Fprintln(stderr, "screencap: no frame")
+		return 1
+	}
+	if len(args) != 0 && args[0] == "-p" {
+		if err := png.Encode(stdout, frame); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	img, ok := frame.(*image.NRGBA)
+	if !ok {
+		img = image.NewNRGBA(frame.Bounds())
+		draw.Draw(img, img.Bounds(), frame, frame.Bounds().Min, draw.Src)
+	}
+	b := img.Bounds()
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(b.Dx()))
+	binary.LittleEndian.PutUint32(header[4:8], uint32(b.Dy()))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(RGBA_8888))
+	binary.LittleEndian.PutUint32(header[12:16], 1)
+	_, _ = stdout.Write(header)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		o := img.PixOffset(b.Min.X, y)
+		_, _ = stdout.Write(img.Pix[o : o+b.Dx()*4])
+	}
+	return 0
+}
+
+func (d *FakeDevice) input(args []string, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: input [<source>] <command> [<arg>...]")
+		return 1
+	}
+	d.rw.Lock()
+	d.inputs = append(d.inputs, "input "+strings.Join(args, " "))
+	d.rw.Unlock()
+	if d.scene != nil {
+		d.scene.Input(args)
+	}
+	return 0
+}
+
+func (d *FakeDevice) settingsCmd(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprintln(stderr, "Invalid command")
+		return 1
+	}
+	ns := Namespace(args[1])
+	if ns != System && ns != Secure && ns != Global {
+		fmt.Fprintf(stderr, "Invalid namespace '%s'\n", ns)
+		return 1
+	}
+
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	switch {
+	case args[0] == "get" && len(args) == 3:
+		v, ok := d.settings[ns][args[2]]
+		if !ok {
+			v = "null"
+		}
+		fmt.Fprintln(stdout, v)
+	case args[0] == "put" && len(args) >= 4:
+		d.setSetting(ns, args[2], args[3])
+		if ns == System && args[2] == "user_rotation" {
+			if r, err := strconv.Atoi(args[3]); err == nil {
+				d.rotation = Rotation(r).norm()
+			}
+		}
+	case args[0] == "delete" && len(args) == 3:
+		n := 0
+		if _, ok := d.settings[ns][args[2]]; ok {
+			n = 1
+			delete(d.settings[ns], args[2])
+		}
+		fmt.Fprintf(stdout, "Deleted %d rows\n", n)
+	case args[0] == "list":
+		keys := make([]string, 0, len(d.settings[ns]))
+		for k := range d.settings[ns] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(stdout, "%s=%s\n", k, d.settings[ns][k])
+		}
+	default:
+		fmt.Fprintln(stderr, "Invalid command")
+		return 1
+	}
+	return 0
+}
+
+func (d *FakeDevice) am(args []string, stderr io.Writer) int {
+	if len(args) == 0 {
+		return 1
+	}
+
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	switch args[0] {
+	case "start":
+		for i := 1; i < len(args)-1; i++ {
+			if args[i] == "-n" {
+				d.top = strings.SplitN(args[i+1], "/", 2)[0]
+				return 0
+			}
+		}
+		fmt.Fprintln(stderr, "Error: no component")
+		return 1
+	case "force-stop":
+		if len(args) > 1 && args[1] == d.top {
+			d.top = FakeLauncher
+		}
+		return 0
+	}
+	return 0
+}
+
+func (d *FakeDevice) dumpsys(args []string, stdout io.Writer) int {
+	if len(args) == 0 {
+		return 0
+	}
+	d.rw.Lock()
+	top, rotation := d.top, d.rotation
+	d.rw.Unlock()
+
+	switch args[0] {
+	case "input":
+		fmt.Fprintln(stdout, "INPUT MANAGER (dumpsys input)")
+		fmt.Fprintln(stdout, "Input Reader State:")
+		fmt.Fprintln(stdout, "  Device 2: fake-touchscreen")
+		fmt.Fprintln(stdout, "    Touch Input Mapper (mode - DIRECT):")
+		fmt.Fprintf(stdout, "      SurfaceOrientation: %d\n", rotation)
+		return 0
+	case "activity":
+	default:
+		return 0
+	}
+	fmt.Fprintln(stdout, "ACTIVITY MANAGER RUNNING PROCESSES (dumpsys activity processes)")
+	fmt.Fprintf(stdout, "    Proc # 0: fg     T/A/TOP  LCM  t: 0 10000:%s/u0a100 (top-activity)\n", top)
+	return 0
+}
+
+func (d *FakeDevice) wm(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		return 1
+	}
+
+	d.rw.Lock()
+	defer d.rw.Unlock()
+	switch args[0] {
+	case "size":
+		if len(args) > 1 {
+			if args[1] == "reset" {
+				d.size = image.Point{}
+				return 0
+			}
+			var w, h int
+			if _, err := fmt.Sscanf(args[1], "%dx%d", &w, &h); err != nil {
+				fmt.Fprintln(stderr, "Error: bad size")
+				return 1
+			}
+			d.size = image.Point{w, h}
+			return 0
+		}
+		fmt.Fprintf(stdout, "Physical size: %dx%d\n", d.physical.X, d.physical.Y)
+		if d.size != (image.Point{}) {
+			fmt.Fprintf(stdout, "Override size: %dx%d\n", d.size.X, d.size.Y)
+		}
+	case "density":
+		if len(args) > 1 {
+			if args[1] == "reset" {
+				d.dpi = 0
+				return 0
+			}
+			dpi, err := strconv.Atoi(args[1])
+			if err != nil {
+				return 1
+			}
+			d.dpi = dpi
+			return 0
+		}
+		fmt.Fprintf(stdout, "Physical density: %d\n", fakeDensity)
+		if d.dpi != 0 {
+			fmt.Fprintf(stdout, "Override density: %d\n", d.dpi)
+		}
+	}
+	return 0
+}
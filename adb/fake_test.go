@@ -0,0 +1,221 @@
+package adb
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func fakeFrame(c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 108, 192))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func fakeClient(t *testing.T, d *FakeDevice) *ADB {
+	t.Helper()
+	c := d.Client(1024 * 1024)
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// fakeOutput returns a handler printing a canned capture.
+func fakeOutput(output string) FakeHandler {
+	return func(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		io.WriteString(stdout, output)
+		return 0
+	}
+}
+
+func TestFakeScreencap(t *testing.T) {
+	red := fakeFrame(color.NRGBA{255, 0, 0, 255})
+	d := NewFakeDevice("fake", NewFrameScene(red))
+	c := fakeClient(t, d)
+
+	img, err := c.Screencap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != red.Bounds() {
+		t.Fatalf("expected bounds %s, got %s", red.Bounds(), img.Bounds())
+	}
+	if !reflect.DeepEqual(img.Pix, red.Pix) {
+		t.Error("screencap does not match the scene frame")
+	}
+
+	// the session must still be usable after the binary output
+	if _, err := c.Setting(System, "screen_brightness"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFakeTapScene(t *testing.T) {
+	red := fakeFrame(color.NRGBA{255, 0, 0, 255})
+	blue := fakeFrame(color.NRGBA{0, 0, 255, 255})
+	scene := NewSceneGraph(
+		"menu",
+		&SceneNode{
+			Name:  "menu",
+			Image: red,
+			Edges: []SceneEdge{{Tap: image.Rect(10, 10, 50, 50), Next: "game"}},
+		},
+		&SceneNode{
+			Name:  "game",
+			Image: blue,
+			Edges: []SceneEdge{{Key: "KEYCODE_BACK", Next: "menu"}},
+		},
+	)
+	d := NewFakeDevice("fake", scene)
+	c := fakeClient(t, d)
+
+	if err := c.Tap(80, 80); err != nil {
+		t.Fatal(err)
+	}
+	if cur := scene.Current(); cur != "menu" {
+		t.Errorf("tap outside edge moved scene to %s", cur)
+	}
+	if err := c.Tap(20, 20); err != nil {
+		t.Fatal(err)
+	}
+	if cur := scene.Current(); cur != "game" {
+		t.Fatalf("expected scene game, got %s", cur)
+	}
+	img, err := c.Screencap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(img.Pix, blue.Pix) {
+		t.Error("screencap does not show the new scene")
+	}
+
+	if err := c.KeyEvent("KEYCODE_BACK"); err != nil {
+		t.Fatal(err)
+	}
+	if cur := scene.Current(); cur != "menu" {
+		t.Errorf("expected scene menu, got %s", cur)
+	}
+
+	exp := []string{"input tap 80 80", "input tap 20 20", "input keyevent KEYCODE_BACK"}
+	if inputs := d.Inputs(); !reflect.DeepEqual(inputs, exp) {
+		t.Errorf("expected inputs %q, got %q", exp, inputs)
+	}
+}
+
+func TestFakeOrientation(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.SetPhysicalSize(108, 192)
+	c := fakeClient(t, d)
+
+	if err := c.SetRotation(Rotation90); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.Rotation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != Rotation90 {
+		t.Errorf("expected rotation %d, got %d", Rotation90, r)
+	}
+
+	if err := c.SetOrientationAware(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Tap(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"input tap 0 107"}
+	if inputs := d.Inputs(); !reflect.DeepEqual(inputs, exp) {
+		t.Errorf("expected inputs %q, got %q", exp, inputs)
+	}
+}
+
+func TestFakeSettings(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	c := fakeClient(t, d)
+
+	if v, err := c.Setting(Global, "adb_enabled"); err != nil || v != "null" {
+		t.Errorf("expected null, got %q, %v", v, err)
+	}
+	if err := c.SetSetting(Global, "adb_enabled", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := d.Setting(Global, "adb_enabled"); !ok || v != "1" {
+		t.Errorf("device setting not updated: %q, %v", v, ok)
+	}
+
+	d.SetSetting(Secure, "android_id", "fake-id")
+	if v, err := c.Setting(Secure, "android_id"); err != nil || v != "fake-id" {
+		t.Errorf("expected fake-id, got %q, %v", v, err)
+	}
+}
+
+func TestFakeAmStart(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	c := fakeClient(t, d)
+
+	if pkg, err := c.TopActivity(); err != nil || pkg != FakeLauncher {
+		t.Errorf("expected %s, got %q, %v", FakeLauncher, pkg, err)
+	}
+	if err := c.AmStart("com.example.game", "MainActivity"); err != nil {
+		t.Fatal(err)
+	}
+	if pkg, err := c.TopActivity(); err != nil || pkg != "com.example.game" {
+		t.Errorf("expected com.example.game, got %q, %v", pkg, err)
+	}
+	if top := d.TopActivity(); top != "com.example.game" {
+		t.Errorf("device top activity is %s", top)
+	}
+}
+
+func TestFakeCommandNotFound(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	c := fakeClient(t, d)
+
+	err := c.Run("frobnicate --now", nil, nil)
+	var ce *CmdError
+	if !errors.As(err, &ce) || ce.ExitCode != 127 {
+		t.Fatalf("expected exit code 127, got %v", err)
+	}
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("expected ErrCommandNotFound, got %v", err)
+	}
+	if Recovery(err) != RecoveryAbort {
+		t.Errorf("expected RecoveryAbort, got %d", Recovery(err))
+	}
+
+	// the session survives a failed command
+	if err := c.Run("true", nil, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFakeConcurrentDevices(t *testing.T) {
+	// the orchestrator drives several devices at once, the package level
+	// parsers must be safe for concurrent use
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		d := NewFakeDevice(fmt.Sprintf("fake-%d", i), nil)
+		c := fakeClient(t, d)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if pkg, err := c.TopActivity(); err != nil || pkg != FakeLauncher {
+				t.Errorf("expected %s, got %q, %v", FakeLauncher, pkg, err)
+			}
+			if err := c.Run("frobnicate", nil, nil); !errors.Is(err, ErrCommandNotFound) {
+				t.Errorf("expected ErrCommandNotFound, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
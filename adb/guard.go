@@ -0,0 +1,462 @@
+package adb
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Region is a rectangle input is not allowed to touch, either absolute or
+// relative to the display size. Coordinates are in the same space as the
+// arguments to Tap and Drag, i.e.: natural coordinates for orientation
+// aware clients, current coordinates otherwise.
+type Region struct {
+	Name string
+
+	rect     image.Rectangle
+	rel      [4]float64
+	relative bool
+}
+
+func ForbidRect(name string, r image.Rectangle) Region {
+	return Region{Name: name, rect: r.Canon()}
+}
+
+// ForbidRelative creates a region relative to the display size (0-1).
+func ForbidRelative(name string, x0, y0, x1, y1 float64) Region {
+	return Region{Name: name, rel: [4]float64{x0, y0, x1, y1}, relative: true}
+}
+
+func (r Region) Rect(size image.Point) image.Rectangle {
+	if !r.relative {
+		return r.rect
+	}
+	w, h := float64(size.X), float64(size.Y)
+	return image.Rect(int(r.rel[0]*w), int(r.rel[1]*h), int(r.rel[2]*w), int(r.rel[3]*h))
+}
+
+type ForbiddenError struct {
+	Region Region
+	Point  image.Point
+	Call   Call
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("%s at %s is inside forbidden region '%s'", e.Call.Helper, e.Point, e.Region.Name)
+}
+
+type RateLimitError struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("input rate limit of %d per %s exceeded", e.Limit, e.Window)
+}
+
+var ErrKillSwitch = errors.New("input halted by kill switch")
+
+// ErrUnguarded is returned for touch input or shell scripts the guard can
+// not determine the coordinates of (e.g. sendevent, sh <file>) while
+// regions are forbidden.
+var ErrUnguarded = errors.New("unguarded touch input")
+
+// Guard is a middleware that rejects input commands that hit forbidden
+// regions, exceed a budget or occur after the kill switch was triggered.
+// Besides Call.Points, the coordinates of input commands (input tap, swipe,
+// draganddrop and motionevent, also as /system/bin/input, cmd input or
+// command substitution) in Batch, Run and scripts are checked.
+// Shells that do not get their commands inline (sh <file>, piping to sh,
+// source) are rejected, as is sendevent unless the call carries Points
+// (e.g. TouchPlayer).
+// Calls that are not marked as Call.Input are only guarded when they
+// contain input commands or such shells.
+type Guard struct {
+	adb *ADB
+
+	rw       sync.Mutex
+	regions  []Region
+	limit    int
+	window   time.Duration
+	history  []time.Time
+	killFile string
+	killed   bool
+	size     image.Point
+	rotation Rotation
+	rotated  time.Time
+}
+
+// NewGuard creates a guard, client is used to determine the display size
+// for relative regions, add it to the client with Use(guard.Middleware()).
+func NewGuard(client *ADB) *Guard {
+	return &Guard{adb: client, window: time.Minute}
+}
+
+func (g *Guard) Forbid(r ...Region) {
+	g.rw.Lock()
+	g.regions = append(g.regions, r...)
+	g.rw.Unlock()
+}
+
+// SetBudget allows at most n input commands per minute, 0 disables.
+func (g *Guard) SetBudget(n int) {
+	g.rw.Lock()
+	g.limit = n
+	g.rw.Unlock()
+}
+
+// SetKillFile halts all input once path exists.
+func (g *Guard) SetKillFile(path string) {
+	g.rw.Lock()
+	g.killFile = path
+	g.rw.Unlock()
+}
+
+// KillOnSignal halts all input once one of the given signals is received.
+func (g *Guard) KillOnSignal(sig ...os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig...)
+	go func() {
+		<-c
+		g.Kill()
+	}()
+}
+
+// Kill halts all input until Resume is called.
+func (g *Guard) Kill() {
+	g.rw.Lock()
+	g.killed = true
+	g.rw.Unlock()
+}
+
+func (g *Guard) Resume() {
+	g.rw.Lock()
+	g.killed = false
+	g.rw.Unlock()
+}
+
+func (g *Guard) Killed() bool {
+	g.rw.Lock()
+	defer g.rw.Unlock()
+	return g.killed
+}
+
+// rotationTTL is how long the guard caches the display rotation of clients
+// that are not orientation aware.
+const rotationTTL = time.Second
+
+// displaySize returns the size relative regions are resolved against.
+func (g *Guard) displaySize() (image.Point, error) {
+	if o, ok := g.adb.Orientation(); ok {
+		return o.Natural, nil
+	}
+	if g.size == (image.Point{}) {
+		size, err := g.adb.DisplaySize()
+		if err != nil {
+			return size, err
+		}
+		g.size = size
+	}
+	if time.Since(g.rotated) >= rotationTTL {
+		rot, err := g.adb.Rotation()
+		if err != nil {
+			return image.Point{}, err
+		}
+		g.rotation, g.rotated = rot, time.Now()
+	}
+	return Orientation{Natural: g.size, Rotation: g.rotation}.Size(), nil
+}
+
+// inputSources are the optional source arguments of the input command.
+var inputSources = map[string]bool{
+	"keyboard":        true,
+	"mouse":           true,
+	"touchpad":        true,
+	"touchscreen":     true,
+	"touchnavigation": true,
+	"stylus":          true,
+	"trackball":       true,
+	"dpad":            true,
+	"gamepad":         true,
+	"joystick":        true,
+	"rotaryencoder":   true,
+}
+
+// inputShells run commands the guard can only inspect when passed inline
+// with -c (su: when passed any arguments).
+var inputShells = map[string]bool{
+	"sh":     true,
+	"ash":    true,
+	"bash":   true,
+	"dash":   true,
+	"ksh":    true,
+	"mksh":   true,
+	"zsh":    true,
+	"su":     true,
+	"source": true,
+	".":      true,
+}
+
+// inputIgnore are commands whose arguments are never executed, e.g.:
+// 'dumpsys input'.
+var inputIgnore = map[string]bool{
+	"dumpsys": true,
+	"grep":    true,
+	"echo":    true,
+	"printf":  true,
+	"which":   true,
+}
+
+// inputCommands are the input commands found in a shell command.
+type inputCommands struct {
+	// points are the device coordinates touched.
+	points []image.Point
+	found  bool
+	// unparsed is the first touch command or shell invocation whose
+	// coordinates or script could not be determined.
+	unparsed string
+	// sendevent is the first sendevent command.
+	sendevent string
+}
+
+// parseInput finds the input commands in cmd. Any word naming input or
+// sendevent (e.g. /system/bin/input, cmd input, $(input ...)) is considered
+// a command unless it is an argument of a command in inputIgnore.
+func parseInput(cmd string) inputCommands {
+	var c inputCommands
+	split := func(r rune) bool {
+		switch r {
+		case '\n', ';', '&', '|', '(', ')', '`', '$':
+			return true
+		}
+		return false
+	}
+	for _, l := range strings.FieldsFunc(cmd, split) {
+		f := make([]string, 0)
+		for _, w := range strings.Fields(strings.NewReplacer(`'`, "", `"`, "").Replace(l)) {
+			// skip redirects
+			if r := strings.TrimLeft(w, "0123456789"); r != "" && (r[0] == '<' || r[0] == '>') {
+				continue
+			}
+			f = append(f, w)
+		}
+		l = strings.TrimSpace(l)
+
+	words:
+		for i := range f {
+			name := path.Base(f[i])
+			if i != 0 && inputIgnore[path.Base(f[i-1])] {
+				continue
+			}
+			switch {
+			case name == "sendevent":
+				c.found = true
+				if c.sendevent == "" {
+					c.sendevent = l
+				}
+				break words
+			case name == "input":
+				c.found = true
+				p, ok := parseInputArgs(f[i+1:])
+				if !ok {
+					c.unparsed = l
+					return c
+				}
+				c.points = append(c.points, p...)
+				break words
+			case inputShells[name] && !inlineShell(name, f[i+1:]):
+				c.found, c.unparsed = true, l
+				return c
+			}
+		}
+	}
+	return c
+}
+
+// inlineShell reports whether shell name is passed its commands inline.
+func inlineShell(name string, args []string) bool {
+	switch name {
+	case "source", ".":
+		return false
+	case "su":
+		return len(args) != 0
+	}
+	for _, a := range args {
+		if a == "-c" {
+			return true
+		}
+		if !strings.HasPrefix(a, "-") {
+			return false
+		}
+	}
+	return false
+}
+
+// parseInputArgs parses the arguments of a single input command, ok is
+// false for touch commands without (valid) coordinates.
+func parseInputArgs(args []string) (points []image.Point, ok bool) {
+	for len(args) != 0 {
+		if inputSources[args[0]] {
+			args = args[1:]
+			continue
+		}
+		if args[0] == "-d" && len(args) > 1 {
+			args = args[2:]
+			continue
+		}
+		break
+	}
+	if len(args) == 0 {
+		return nil, true
+	}
+
+	var coords []string
+	switch args[0] {
+	case "tap":
+		coords = args[1:]
+		if len(coords) > 2 {
+			coords = coords[:2]
+		}
+	case "swipe", "draganddrop":
+		coords = args[1:]
+		if len(coords) > 4 {
+			coords = coords[:4]
+		}
+	case "motionevent":
+		if len(args) > 2 {
+			coords = args[2:]
+		}
+		if len(coords) > 2 {
+			coords = coords[:2]
+		}
+	default:
+		// text, keyevent, ...
+		return nil, true
+	}
+	if len(coords) == 0 || len(coords)%2 != 0 {
+		return nil, false
+	}
+	for i := 0; i < len(coords); i += 2 {
+		x, errx := strconv.ParseFloat(coords[i], 64)
+		y, erry := strconv.ParseFloat(coords[i+1], 64)
+		if errx != nil || erry != nil {
+			return nil, false
+		}
+		points = append(points, image.Pt(int(x), int(y)))
+	}
+	return points, true
+}
+
+// points returns the points touched by call in the coordinate space of
+// regions.
+func (g *Guard) points(call *Call) ([]image.Point, error) {
+	points := call.Points
+	if len(points) != 0 && call.Script == "" {
+		// an input helper, Cmd only touches Points
+		return points, nil
+	}
+	cmd := call.Cmd
+	if call.Script != "" {
+		// Cmd executes Script
+		cmd = call.Script
+	}
+
+	c := parseInput(cmd)
+	if c.unparsed != "" {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnguarded, c.unparsed)
+	}
+	if c.sendevent != "" && len(points) == 0 {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnguarded, c.sendevent)
+	}
+	if len(c.points) == 0 {
+		return points, nil
+	}
+
+	all := make([]image.Point, 0, len(points)+len(c.points))
+	all = append(all, points...)
+	o, ok := g.adb.Orientation()
+	for _, p := range c.points {
+		if ok {
+			p = o.Inverse(p)
+		}
+		all = append(all, p)
+	}
+	return all, nil
+}
+
+func (g *Guard) check(call *Call) error {
+	g.rw.Lock()
+	defer g.rw.Unlock()
+
+	if !g.killed && g.killFile != "" {
+		if _, err := os.Stat(g.killFile); err == nil {
+			g.killed = true
+		}
+	}
+	if g.killed {
+		return ErrKillSwitch
+	}
+
+	if len(g.regions) != 0 {
+		points, err := g.points(call)
+		if err != nil {
+			return err
+		}
+		var size image.Point
+		for _, r := range g.regions {
+			if len(points) == 0 {
+				break
+			}
+			if r.relative && size == (image.Point{}) {
+				if size, err = g.displaySize(); err != nil {
+					return err
+				}
+			}
+			rect := r.Rect(size)
+			for _, p := range points {
+				if p.In(rect) {
+					return &ForbiddenError{Region: r, Point: p, Call: *call}
+				}
+			}
+		}
+	}
+
+	if g.limit > 0 {
+		now := time.Now()
+		n := 0
+		for _, t := range g.history {
+			if now.Sub(t) < g.window {
+				g.history[n] = t
+				n++
+			}
+		}
+		g.history = g.history[:n]
+		if len(g.history) >= g.limit {
+			return &RateLimitError{Limit: g.limit, Window: g.window}
+		}
+		g.history = append(g.history, now)
+	}
+
+	return nil
+}
+
+func (g *Guard) Middleware() Middleware {
+	return func(call *Call, next func() error) error {
+		if !call.Input {
+			if !parseInput(call.Cmd).found {
+				return next()
+			}
+		}
+		if err := g.check(call); err != nil {
+			return err
+		}
+		return next()
+	}
+}
@@ -0,0 +1,119 @@
+package adb
+
+import (
+	"errors"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestParseInput(t *testing.T) {
+	pts := func(p ...int) []image.Point {
+		l := make([]image.Point, 0, len(p)/2)
+		for i := 0; i < len(p); i += 2 {
+			l = append(l, image.Pt(p[i], p[i+1]))
+		}
+		return l
+	}
+	tests := []struct {
+		cmd       string
+		points    []image.Point
+		found     bool
+		unparsed  bool
+		sendevent bool
+	}{
+		{cmd: "input tap 10 20 >/dev/null 2>&1", points: pts(10, 20), found: true},
+		{cmd: "input touchscreen swipe 1 2 3 4 300", points: pts(1, 2, 3, 4), found: true},
+		{cmd: "input -d 0 stylus draganddrop 1 2 3 4", points: pts(1, 2, 3, 4), found: true},
+		{cmd: "input motionevent DOWN 5 6", points: pts(5, 6), found: true},
+		{cmd: "input text hello", found: true},
+		{cmd: "input keyevent KEYCODE_HOME", found: true},
+		{cmd: "input tap 10.5 20.5", points: pts(10, 20), found: true},
+		{cmd: "input tap x 20", found: true, unparsed: true},
+		{cmd: "input tap 10", found: true, unparsed: true},
+
+		// bypasses
+		{cmd: "/system/bin/input tap 1 2", points: pts(1, 2), found: true},
+		{cmd: "cmd input tap 1 2", points: pts(1, 2), found: true},
+		{cmd: "timeout 5 input tap 1 2", points: pts(1, 2), found: true},
+		{cmd: "nohup input tap 1 2 &", points: pts(1, 2), found: true},
+		{cmd: "busybox input tap 1 2", points: pts(1, 2), found: true},
+		{cmd: "echo `input tap 1 2`", points: pts(1, 2), found: true},
+		{cmd: "echo $(input tap 1 2)", points: pts(1, 2), found: true},
+		{cmd: "true && input tap 1 2 || input tap 3 4", points: pts(1, 2, 3, 4), found: true},
+		{cmd: "input tap $X 2", found: true, unparsed: true},
+
+		// shells
+		{cmd: `sh -c 'input tap 1 2'`, points: pts(1, 2), found: true},
+		{cmd: `su 0 sh -c "input tap 1 2; input tap 3 4"`, points: pts(1, 2, 3, 4), found: true},
+		{cmd: `su -c 'id -u' 2>/dev/null </dev/null`},
+		{cmd: "run-as com.example sh -c 'input swipe 1 2 3 4'", points: pts(1, 2, 3, 4), found: true},
+		{cmd: "sh /data/local/tmp/x.sh", found: true, unparsed: true},
+		{cmd: "echo aW5wdXQ= | base64 -d | sh", found: true, unparsed: true},
+		{cmd: "sh < /sdcard/x", found: true, unparsed: true},
+		{cmd: ". /sdcard/x", found: true, unparsed: true},
+		{cmd: "su", found: true, unparsed: true},
+
+		// sendevent
+		{cmd: "sendevent /dev/input/event2 3 53 100", found: true, sendevent: true},
+
+		// not input
+		{cmd: "dumpsys input 2>&1 | grep -E 'SurfaceOrientation|orientation=' | head -n 1"},
+		{cmd: "dumpsys input"},
+		{cmd: "getevent -lp 2>/dev/null"},
+		{cmd: "settings put system user_rotation 1"},
+		{cmd: "echo input tap 1 2"},
+		// any word named input is a command, harmless as there are no
+		// coordinates to check
+		{cmd: "ls /dev/input", found: true},
+	}
+	for _, test := range tests {
+		c := parseInput(test.cmd)
+		if !reflect.DeepEqual(c.points, test.points) {
+			t.Errorf("%q: expected points %v, got %v", test.cmd, test.points, c.points)
+		}
+		if c.found != test.found {
+			t.Errorf("%q: expected found %t, got %t", test.cmd, test.found, c.found)
+		}
+		if (c.unparsed != "") != test.unparsed {
+			t.Errorf("%q: expected unparsed %t, got %q", test.cmd, test.unparsed, c.unparsed)
+		}
+		if (c.sendevent != "") != test.sendevent {
+			t.Errorf("%q: expected sendevent %t, got %q", test.cmd, test.sendevent, c.sendevent)
+		}
+	}
+}
+
+func TestGuard(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.SetPhysicalSize(108, 192)
+	c := fakeClient(t, d)
+	g := NewGuard(c)
+	g.Forbid(ForbidRect("corner", image.Rect(0, 0, 10, 10)))
+	c.Use(g.Middleware())
+
+	var forbidden *ForbiddenError
+	if err := c.Tap(5, 5); !errors.As(err, &forbidden) {
+		t.Errorf("Tap: expected ForbiddenError, got %v", err)
+	}
+	if err := c.Tap(50, 50); err != nil {
+		t.Errorf("Tap: %s", err)
+	}
+	if err := c.Run("/system/bin/input tap 5 5", nil, nil); !errors.As(err, &forbidden) {
+		t.Errorf("Run: expected ForbiddenError, got %v", err)
+	}
+	if _, err := c.Batch("true", "cmd input tap 5 5"); !errors.As(err, &forbidden) {
+		t.Errorf("Batch: expected ForbiddenError, got %v", err)
+	}
+	if err := c.Run("echo aW5wdXQ= | base64 -d | sh", nil, nil); !errors.Is(err, ErrUnguarded) {
+		t.Errorf("Run: expected ErrUnguarded, got %v", err)
+	}
+	if err := c.Run("dumpsys input >/dev/null", nil, nil); err != nil {
+		t.Errorf("Run: %s", err)
+	}
+
+	exp := []string{"input tap 50 50"}
+	if inputs := d.Inputs(); !reflect.DeepEqual(inputs, exp) {
+		t.Errorf("expected inputs %q, got %q", exp, inputs)
+	}
+}
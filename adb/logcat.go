@@ -0,0 +1,51 @@
+package adb
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// LogLine is a single logcat line in threadtime format.
+type LogLine struct {
+	Time    time.Time
+	PID     int
+	TID     int
+	Level   byte
+	Tag     string
+	Message string
+}
+
+var logLineRE = regexp.MustCompile(
+	`^(\d\d-\d\d \d\d:\d\d:\d\d\.\d{3})\s+(\d+)\s+(\d+)\s+([VDIWEFA])\s+(.*?)\s*: (.*)$`,
+)
+
+// ParseLogLine parses a logcat line in threadtime format, the year is
+// assumed to be the current one.
+func ParseLogLine(l string) (LogLine, bool) {
+	var line LogLine
+	res := logLineRE.FindStringSubmatch(l)
+	if len(res) != 7 {
+		return line, false
+	}
+	now := time.Now()
+	t, err := time.ParseInLocation("01-02 15:04:05.000", res[1], time.Local)
+	if err == nil {
+		line.Time = t.AddDate(now.Year(), 0, 0)
+	}
+	line.PID, _ = strconv.Atoi(res[2])
+	line.TID, _ = strconv.Atoi(res[3])
+	line.Level = res[4][0]
+	line.Tag = res[5]
+	line.Message = res[6]
+	return line, true
+}
+
+// LogcatTail returns the last n lines of the default logcat buffers.
+func (adb *ADB) LogcatTail(n int) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run(fmt.Sprintf("logcat -d -v threadtime -t %d", n), buf, nil)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,218 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stream is an android audio stream type (AudioManager.STREAM_*).
+type Stream int
+
+const (
+	StreamVoiceCall     Stream = 0
+	StreamSystem        Stream = 1
+	StreamRing          Stream = 2
+	StreamMusic         Stream = 3
+	StreamAlarm         Stream = 4
+	StreamNotification  Stream = 5
+	StreamAccessibility Stream = 10
+)
+
+type Volume struct {
+	Level int
+	Min   int
+	Max   int
+}
+
+var volumeRE = regexp.MustCompile(`volume is (\d+) in range \[(\d+)\.\.(\d+)\]`)
+
+func (adb *ADB) Volume(stream Stream) (Volume, error) {
+	var v Volume
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run(fmt.Sprintf("cmd media_session volume --stream %d --get 2>&1", stream), buf, nil)
+	if err != nil {
+		return v, err
+	}
+	res := volumeRE.FindStringSubmatch(buf.String())
+	if len(res) != 4 {
+		return v, errors.New("could not parse volume")
+	}
+	v.Level, _ = strconv.Atoi(res[1])
+	v.Min, _ = strconv.Atoi(res[2])
+	v.Max, _ = strconv.Atoi(res[3])
+	return v, nil
+}
+
+func (adb *ADB) SetVolume(stream Stream, level int) error {
+	return adb.runInput(
+		fmt.Sprintf("cmd media_session volume --stream %d --set %d >/dev/null 2>&1", stream, level),
+	)
+}
+
+// SetMuted mutes a stream by setting its volume to its minimum, unmuting
+// restores the level it had when it was muted by this client.
+func (adb *ADB) SetMuted(stream Stream, mute bool) error {
+	if !mute {
+		level, ok := adb.muted[stream]
+		if !ok {
+			return nil
+		}
+		if err := adb.SetVolume(stream, level); err != nil {
+			return err
+		}
+		delete(adb.muted, stream)
+		return nil
+	}
+
+	if _, ok := adb.muted[stream]; ok {
+		return nil
+	}
+	v, err := adb.Volume(stream)
+	if err != nil {
+		return err
+	}
+	if err := adb.SetVolume(stream, v.Min); err != nil {
+		return err
+	}
+	if adb.muted == nil {
+		adb.muted = make(map[Stream]int)
+	}
+	adb.muted[stream] = v.Level
+	return nil
+}
+
+// ToggleMute toggles mute of the currently active stream.
+func (adb *ADB) ToggleMute() error { return adb.KeyEvent("KEYCODE_VOLUME_MUTE") }
+
+type MediaKey string
+
+const (
+	MediaPlayPause MediaKey = "KEYCODE_MEDIA_PLAY_PAUSE"
+	MediaPlay      MediaKey = "KEYCODE_MEDIA_PLAY"
+	MediaPause     MediaKey = "KEYCODE_MEDIA_PAUSE"
+	MediaStop      MediaKey = "KEYCODE_MEDIA_STOP"
+	MediaNext      MediaKey = "KEYCODE_MEDIA_NEXT"
+	MediaPrevious  MediaKey = "KEYCODE_MEDIA_PREVIOUS"
+)
+
+func (adb *ADB) MediaKey(key MediaKey) error { return adb.KeyEvent(string(key)) }
+
+// PlaybackState is a media session playback state (PlaybackState.STATE_*).
+type PlaybackState int
+
+const (
+	PlaybackNone PlaybackState = iota
+	PlaybackStopped
+	PlaybackPaused
+	PlaybackPlaying
+	PlaybackFastForwarding
+	PlaybackRewinding
+	PlaybackBuffering
+	PlaybackError
+	PlaybackConnecting
+	PlaybackSkippingToPrevious
+	PlaybackSkippingToNext
+	PlaybackSkippingToQueueItem
+)
+
+func (p PlaybackState) String() string {
+	switch p {
+	case PlaybackNone:
+		return "none"
+	case PlaybackStopped:
+		return "stopped"
+	case PlaybackPaused:
+		return "paused"
+	case PlaybackPlaying:
+		return "playing"
+	case PlaybackFastForwarding:
+		return "fast forwarding"
+	case PlaybackRewinding:
+		return "rewinding"
+	case PlaybackBuffering:
+		return "buffering"
+	case PlaybackError:
+		return "error"
+	case PlaybackConnecting:
+		return "connecting"
+	case PlaybackSkippingToPrevious:
+		return "skipping to previous"
+	case PlaybackSkippingToNext:
+		return "skipping to next"
+	case PlaybackSkippingToQueueItem:
+		return "skipping to queue item"
+	}
+	return "unknown"
+}
+
+type MediaSession struct {
+	Package     string
+	Active      bool
+	State       PlaybackState
+	Position    time.Duration
+	Speed       float64
+	Description string
+}
+
+var playbackStateRE = regexp.MustCompile(`state=PlaybackState \{state=(\d+), position=(-?\d+),.*?speed=([-\d.]+)`)
+
+// MediaSessions parses the media sessions from dumpsys media_session, in
+// priority order.
+func (adb *ADB) MediaSessions() ([]MediaSession, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("dumpsys media_session", buf, nil); err != nil {
+		return nil, err
+	}
+
+	list := make([]MediaSession, 0)
+	var cur *MediaSession
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(l, "package="):
+			list = append(list, MediaSession{Package: strings.TrimPrefix(l, "package=")})
+			cur = &list[len(list)-1]
+		case cur == nil:
+		case strings.HasPrefix(l, "active="):
+			cur.Active = l == "active=true"
+		case strings.HasPrefix(l, "state=PlaybackState"):
+			res := playbackStateRE.FindStringSubmatch(l)
+			if len(res) != 4 {
+				continue
+			}
+			st, _ := strconv.Atoi(res[1])
+			pos, _ := strconv.ParseInt(res[2], 10, 64)
+			cur.State = PlaybackState(st)
+			cur.Position = time.Duration(pos) * time.Millisecond
+			cur.Speed, _ = strconv.ParseFloat(res[3], 64)
+		case strings.HasPrefix(l, "metadata:"):
+			if i := strings.Index(l, "description="); i >= 0 && l[i+len("description="):] != "null" {
+				cur.Description = l[i+len("description="):]
+			}
+		}
+	}
+
+	return list, s.Err()
+}
+
+// MediaSession returns the session of the given package, or the first
+// active session if pkg is empty.
+func (adb *ADB) MediaSession(pkg string) (MediaSession, bool, error) {
+	list, err := adb.MediaSessions()
+	if err != nil {
+		return MediaSession{}, false, err
+	}
+	for _, s := range list {
+		if (pkg == "" && s.Active) || (pkg != "" && s.Package == pkg) {
+			return s, true, nil
+		}
+	}
+	return MediaSession{}, false, nil
+}
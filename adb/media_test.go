@@ -0,0 +1,98 @@
+package adb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pixel 4a, android 12, trimmed
+const mediaSessionDump = `MEDIA SESSION SERVICE (dumpsys media_session)
+
+3 sessions listeners.
+Global priority session is null
+User Records:
+Record for full_user=0
+  Volume key long-press listener: null
+  Volume key long-press listener package: 
+  Media key event receiver: null
+  Media button session is com.spotify.music/spotify-media-session (userId=0)
+  Sessions Stack - have 3 sessions:
+    spotify-media-session com.spotify.music/spotify-media-session (userId=0)
+      ownerPid=12345, ownerUid=10234, userId=0
+      package=com.spotify.music
+      launchIntent=PendingIntent{2a3b4c5: PendingIntentRecord{6d7e8f9 com.spotify.music startActivity}}
+      mediaButtonReceiver=MBR {pi=PendingIntent{0a1b2c3: PendingIntentRecord{4d5e6f7 com.spotify.music broadcastIntent}}, type=1}
+      active=true
+      flags=3
+      rating type=0
+      controllers: 2
+      state=PlaybackState {state=3, position=83215, buffered position=0, speed=1.0, updated=5209315, actions=2360143, custom actions=[Action:mName='Like, mIcon=2131231120, mExtras=null], active item id=-1, error=null}
+      audioAttrs=AudioAttributes: usage=USAGE_MEDIA content=CONTENT_TYPE_MUSIC flags=0x800 tags= bundle=null
+      volumeType=1, controlType=2, max=0, current=0
+      metadata: size=14, description=Blinding Lights, The Weeknd, After Hours
+      queueTitle=null, size=0
+    MediaSessionCompat com.google.android.youtube/YouTube (userId=0)
+      ownerPid=23456, ownerUid=10189, userId=0
+      package=com.google.android.youtube
+      launchIntent=null
+      mediaButtonReceiver=null
+      active=false
+      flags=3
+      rating type=0
+      controllers: 1
+      state=PlaybackState {state=2, position=12000, buffered position=48000, speed=0.0, updated=5100211, actions=3669711, custom actions=[], active item id=-1, error=null}
+      audioAttrs=AudioAttributes: usage=USAGE_MEDIA content=CONTENT_TYPE_MOVIE flags=0x800 tags= bundle=null
+      volumeType=1, controlType=2, max=0, current=0
+      metadata: size=0, description=null
+      queueTitle=null, size=0
+    com.example.game/GameSession (userId=0)
+      ownerPid=34567, ownerUid=10211, userId=0
+      package=com.example.game
+      launchIntent=null
+      mediaButtonReceiver=null
+      active=false
+      flags=0
+      rating type=0
+      controllers: 0
+      state=null
+      audioAttrs=AudioAttributes: usage=USAGE_GAME content=CONTENT_TYPE_SONIFICATION flags=0x800 tags= bundle=null
+      volumeType=1, controlType=2, max=0, current=0
+      metadata: null
+      queueTitle=null, size=0
+`
+
+func TestMediaSessions(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.Handle("dumpsys", fakeOutput(mediaSessionDump))
+	c := fakeClient(t, d)
+
+	list, err := c.MediaSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []MediaSession{
+		{
+			Package:     "com.spotify.music",
+			Active:      true,
+			State:       PlaybackPlaying,
+			Position:    83215 * time.Millisecond,
+			Speed:       1,
+			Description: "Blinding Lights, The Weeknd, After Hours",
+		},
+		{
+			Package:  "com.google.android.youtube",
+			State:    PlaybackPaused,
+			Position: 12 * time.Second,
+		},
+		{Package: "com.example.game"},
+	}
+	if !reflect.DeepEqual(list, exp) {
+		t.Errorf("expected\n%+v\ngot\n%+v", exp, list)
+	}
+
+	s, ok, err := c.MediaSession("")
+	if err != nil || !ok || s.Package != "com.spotify.music" {
+		t.Errorf("expected active com.spotify.music, got %+v, %t, %v", s, ok, err)
+	}
+}
@@ -0,0 +1,311 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicks is USER_HZ which is 100 on all android devices.
+const clockTicks = 100
+
+type MemInfo struct {
+	// All values in KiB.
+	TotalPSS   int
+	JavaHeap   int
+	NativeHeap int
+}
+
+type FrameStats struct {
+	Total        int
+	Janky        int
+	Percentile50 time.Duration
+	Percentile90 time.Duration
+	Percentile95 time.Duration
+	Percentile99 time.Duration
+}
+
+// JankRatio returns the ratio of janky frames.
+func (f FrameStats) JankRatio() float64 {
+	if f.Total == 0 {
+		return 0
+	}
+	return float64(f.Janky) / float64(f.Total)
+}
+
+type Sample struct {
+	Time    time.Time
+	Package string
+	PID     int
+	// CPU usage since the previous sample where 1 equals one fully used
+	// core.
+	CPU    float64
+	Mem    MemInfo
+	Frames FrameStats
+}
+
+func (adb *ADB) PidOf(pkg string) (int, error) {
+	v, err := adb.grep(fmt.Sprintf("pidof -s %s", Quote(pkg)))
+	if err != nil {
+		return 0, err
+	}
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.Fields(v)[0])
+}
+
+func (adb *ADB) MemInfo(pkg string) (MemInfo, error) {
+	var m MemInfo
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run(fmt.Sprintf("dumpsys meminfo %s", Quote(pkg)), buf, nil); err != nil {
+		return m, err
+	}
+
+	first := func(s string) int {
+		f := strings.Fields(s)
+		if len(f) == 0 {
+			return 0
+		}
+		v, _ := strconv.Atoi(f[0])
+		return v
+	}
+
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(l, "Java Heap:"):
+			m.JavaHeap = first(strings.TrimPrefix(l, "Java Heap:"))
+		case strings.HasPrefix(l, "Native Heap:"):
+			m.NativeHeap = first(strings.TrimPrefix(l, "Native Heap:"))
+		case strings.HasPrefix(l, "TOTAL PSS:"):
+			m.TotalPSS = first(strings.TrimPrefix(l, "TOTAL PSS:"))
+		case strings.HasPrefix(l, "TOTAL ") && m.TotalPSS == 0:
+			m.TotalPSS = first(strings.TrimPrefix(l, "TOTAL"))
+		}
+	}
+
+	return m, s.Err()
+}
+
+var gfxPercentileRE = regexp.MustCompile(`^(\d+)th percentile: (\d+)ms`)
+
+func (adb *ADB) FrameStats(pkg string) (FrameStats, error) {
+	var f FrameStats
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run(fmt.Sprintf("dumpsys gfxinfo %s framestats", Quote(pkg)), buf, nil)
+	if err != nil {
+		return f, err
+	}
+
+	s := bufio.NewScanner(buf)
+	s.Buffer(make([]byte, 0, 1024*64), 1024*1024)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(l, "Total frames rendered:"):
+			f.Total, _ = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(l, "Total frames rendered:")))
+		case strings.HasPrefix(l, "Janky frames:"):
+			v := strings.Fields(strings.TrimPrefix(l, "Janky frames:"))
+			if len(v) != 0 {
+				f.Janky, _ = strconv.Atoi(v[0])
+			}
+		default:
+			res := gfxPercentileRE.FindStringSubmatch(l)
+			if len(res) != 3 {
+				continue
+			}
+			ms, _ := strconv.Atoi(res[2])
+			d := time.Duration(ms) * time.Millisecond
+			switch res[1] {
+			case "50":
+				f.Percentile50 = d
+			case "90":
+				f.Percentile90 = d
+			case "95":
+				f.Percentile95 = d
+			case "99":
+				f.Percentile99 = d
+			}
+		}
+	}
+
+	return f, s.Err()
+}
+
+// cpuTicks returns utime+stime of pid in clock ticks.
+func (adb *ADB) cpuTicks(pid int) (int64, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run(fmt.Sprintf("cat /proc/%d/stat", pid), buf, nil); err != nil {
+		return 0, err
+	}
+	d := buf.String()
+	// comm (field 2) can contain spaces, skip past its closing paren.
+	i := strings.LastIndexByte(d, ')')
+	if i < 0 {
+		return 0, errors.New("could not parse /proc/<pid>/stat")
+	}
+	f := strings.Fields(d[i+1:])
+	// utime and stime are fields 14 and 15, f[0] is field 3.
+	if len(f) < 13 {
+		return 0, errors.New("could not parse /proc/<pid>/stat")
+	}
+	utime, err := strconv.ParseInt(f[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseInt(f[12], 10, 64)
+	return utime + stime, err
+}
+
+// Sampler periodically samples process metrics of a package on its own
+// adb session.
+type Sampler struct {
+	adb      *ADB
+	pkg      string
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	lastPID   int
+	lastTicks int64
+	lastTime  time.Time
+}
+
+// NewSampler creates a sampler for pkg using a dedicated session derived
+// from client.
+func NewSampler(client *ADB, pkg string, interval time.Duration) *Sampler {
+	return &Sampler{
+		adb:      client.Session(1024 * 1024 * 5),
+		pkg:      pkg,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Sample takes a single sample. PID is 0 if the package is not running.
+func (s *Sampler) Sample() (Sample, error) {
+	sample := Sample{Time: time.Now(), Package: s.pkg}
+	pid, err := s.adb.PidOf(s.pkg)
+	if err != nil || pid == 0 {
+		s.lastPID = 0
+		return sample, err
+	}
+	sample.PID = pid
+
+	ticks, err := s.adb.cpuTicks(pid)
+	if err != nil {
+		return sample, err
+	}
+	if s.lastPID == pid {
+		elapsed := sample.Time.Sub(s.lastTime).Seconds()
+		if elapsed > 0 {
+			sample.CPU = float64(ticks-s.lastTicks) / clockTicks / elapsed
+		}
+	}
+	s.lastPID, s.lastTicks, s.lastTime = pid, ticks, sample.Time
+
+	if sample.Mem, err = s.adb.MemInfo(s.pkg); err != nil {
+		return sample, err
+	}
+	sample.Frames, err = s.adb.FrameStats(s.pkg)
+	return sample, err
+}
+
+// Run initializes the session and calls cb with a new sample every
+// interval until Stop is called or cb returns an error.
+func (s *Sampler) Run(cb func(Sample) error) error {
+	if err := s.adb.Init(); err != nil {
+		return err
+	}
+	defer s.adb.Close()
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		sample, err := s.Sample()
+		if err != nil {
+			return err
+		}
+		if err := cb(sample); err != nil {
+			return err
+		}
+
+		select {
+		case <-s.stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Sampler) Stop() { s.stopOnce.Do(func() { close(s.stop) }) }
+
+var sampleCSVHeader = []string{
+	"time",
+	"package",
+	"pid",
+	"cpu",
+	"pss_kib",
+	"java_heap_kib",
+	"native_heap_kib",
+	"frames",
+	"janky_frames",
+	"p50_ms",
+	"p90_ms",
+	"p95_ms",
+	"p99_ms",
+}
+
+// WriteSamplesCSV writes samples as csv including a header row.
+func WriteSamplesCSV(w io.Writer, samples []Sample) error {
+	c := csv.NewWriter(w)
+	if err := c.Write(sampleCSVHeader); err != nil {
+		return err
+	}
+	itoa := strconv.Itoa
+	ms := func(d time.Duration) string { return strconv.FormatInt(d.Milliseconds(), 10) }
+	for _, s := range samples {
+		err := c.Write([]string{
+			s.Time.Format(time.RFC3339Nano),
+			s.Package,
+			itoa(s.PID),
+			strconv.FormatFloat(s.CPU, 'f', 4, 64),
+			itoa(s.Mem.TotalPSS),
+			itoa(s.Mem.JavaHeap),
+			itoa(s.Mem.NativeHeap),
+			itoa(s.Frames.Total),
+			itoa(s.Frames.Janky),
+			ms(s.Frames.Percentile50),
+			ms(s.Frames.Percentile90),
+			ms(s.Frames.Percentile95),
+			ms(s.Frames.Percentile99),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteSamplesJSON writes samples as json lines.
+func WriteSamplesJSON(w io.Writer, samples []Sample) error {
+	enc := json.NewEncoder(w)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
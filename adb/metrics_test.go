@@ -0,0 +1,171 @@
+package adb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemInfo(t *testing.T) {
+	tests := []struct {
+		dump string
+		exp  MemInfo
+	}{
+		{
+			// pixel 4a, android 12
+			`Applications Memory Usage (in Kilobytes):
+Uptime: 5219203 Realtime: 5219203
+
+** MEMINFO in pid 12345 [com.example.game] **
+                   Pss  Private  Private  SwapPss      Rss     Heap     Heap     Heap
+                 Total    Dirty    Clean    Dirty    Total     Size    Alloc     Free
+                ------   ------   ------   ------   ------   ------   ------   ------
+  Native Heap    41235    41180        0       12    43216    61440    48756     8563
+  Dalvik Heap    12544    12420        0       24    18240    24576    12288    12288
+ Dalvik Other     2331     2164        0        0     3720
+        Stack     1248     1248        0        0     1256
+       Ashmem       20        0        0        0     1144
+    Other dev       48        0       44        0      432
+     .so mmap    18723     1120    12664        0    64516
+    .jar mmap     1624        0      164        0    30280
+    .apk mmap    10423        0     8932        0    22348
+    .ttf mmap      124        0        0        0      528
+    .dex mmap     9320        4     9308        0     9856
+    .oat mmap      412        0       44        0    13188
+    .art mmap     8211     7760      148        0    19744
+   Other mmap       78        8        4        0     1064
+   EGL mtrack    22464    22464        0        0    22464
+    GL mtrack    14320    14320        0        0    14320
+      Unknown     1324     1320        0        0     1776
+        TOTAL   144500   102608    31304       36   267852    86016    61044    20851
+
+ App Summary
+                       Pss(KB)                        Rss(KB)
+                        ------                         ------
+           Java Heap:    20328                          37984
+         Native Heap:    41180                          43216
+                Code:    32280                         140444
+               Stack:     1248                           1256
+            Graphics:    36784                          36784
+       Private Other:     2092
+              System:    10588
+             Unknown:                                    8168
+
+           TOTAL PSS:   144500            TOTAL RSS:   267852       TOTAL SWAP PSS:       36
+`,
+			MemInfo{TotalPSS: 144500, JavaHeap: 20328, NativeHeap: 41180},
+		},
+		{
+			// emulator, android 9
+			`Applications Memory Usage (in Kilobytes):
+Uptime: 812345 Realtime: 812345
+
+** MEMINFO in pid 4321 [com.example.game] **
+                   Pss  Private  Private  SwapPss     Heap     Heap     Heap
+                 Total    Dirty    Clean    Dirty     Size    Alloc     Free
+                ------   ------   ------   ------   ------   ------   ------
+  Native Heap    18754    18700        0        0    32768    21345     7123
+  Dalvik Heap     6123     6088        0        0    12288     6144     6144
+        Stack      612      612        0        0
+      Unknown      448      448        0        0
+        TOTAL    52310    30112    14008        0    45056    27489    13267
+
+ App Summary
+                       Pss(KB)
+                        ------
+           Java Heap:     9412
+         Native Heap:    18700
+                Code:    15320
+               Stack:      612
+            Graphics:     3120
+       Private Other:     2948
+              System:     2198
+
+               TOTAL:    52310       TOTAL SWAP PSS:        0
+`,
+			MemInfo{TotalPSS: 52310, JavaHeap: 9412, NativeHeap: 18700},
+		},
+		{"No process found for: com.example.game\n", MemInfo{}},
+	}
+	for _, test := range tests {
+		d := NewFakeDevice("fake", nil)
+		d.Handle("dumpsys", fakeOutput(test.dump))
+		c := fakeClient(t, d)
+		m, err := c.MemInfo("com.example.game")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != test.exp {
+			t.Errorf("expected %+v, got %+v", test.exp, m)
+		}
+	}
+}
+
+// pixel 4a, android 12, trimmed
+const gfxinfoDump = `Applications Graphics Acceleration Info:
+Uptime: 5219203 Realtime: 5219203
+
+** Graphics info for pid 12345 [com.example.game] **
+
+Stats since: 5101234567890ns
+Total frames rendered: 3127
+Janky frames: 142 (4.54%)
+Janky frames (legacy): 398 (12.73%)
+50th percentile: 7ms
+90th percentile: 13ms
+95th percentile: 19ms
+99th percentile: 42ms
+Number Missed Vsync: 31
+Number High input latency: 4
+Number Slow UI thread: 87
+Number Slow bitmap uploads: 2
+Number Slow issue draw commands: 40
+Number Frame deadline missed: 142
+50th gpu percentile: 4ms
+90th gpu percentile: 9ms
+95th gpu percentile: 11ms
+99th gpu percentile: 18ms
+HISTOGRAM: 5ms=1523 6ms=512 7ms=301 8ms=196 9ms=121 10ms=98 11ms=74 12ms=61 13ms=50
+GPU HISTOGRAM: 1ms=120 2ms=811 3ms=902 4ms=513 5ms=240 6ms=151
+
+Pipeline=Skia (OpenGL)
+Layout Cache Info:
+  Cache Size: 12 KB
+Font Cache (CPU):
+  Size: 1.04 MB
+
+---PROFILEDATA---
+Flags,FrameTimelineVsyncId,IntendedVsync,Vsync,InputEventId,HandleInputStart,AnimationStart,PerformTraversalsStart,DrawStart,FrameDeadline,FrameStartTime,SyncQueued,SyncStart,IssueDrawCommandsStart,SwapBuffers,FrameCompleted,DequeueBufferDuration,QueueBufferDuration,GpuCompleted,SwapBuffersCompleted,DisplayPresentTime,
+0,51234,5219004123456,5219004123456,0,5219004512345,5219004523456,5219004534567,5219005012345,5219020790122,5219004200000,5219006123456,5219006134567,5219006145678,5219008123456,5219008912345,41234,52345,5219009012345,5219008912345,0,
+---PROFILEDATA---
+
+View hierarchy:
+
+  com.example.game/com.example.game.MainActivity/android.view.ViewRootImpl@3f2e1d0
+  12 views, 18.25 kB of render nodes
+
+Total ViewRootImpl   : 1
+Total attached Views : 12
+Total RenderNode     : 18.25 kB (used) / 40.06 kB (capacity)
+`
+
+func TestFrameStats(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.Handle("dumpsys", fakeOutput(gfxinfoDump))
+	c := fakeClient(t, d)
+
+	f, err := c.FrameStats("com.example.game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := FrameStats{
+		Total:        3127,
+		Janky:        142,
+		Percentile50: 7 * time.Millisecond,
+		Percentile90: 13 * time.Millisecond,
+		Percentile95: 19 * time.Millisecond,
+		Percentile99: 42 * time.Millisecond,
+	}
+	if f != exp {
+		t.Errorf("expected %+v, got %+v", exp, f)
+	}
+}
@@ -0,0 +1,169 @@
+package adb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Call describes a single command passing through the middleware chain.
+type Call struct {
+	// Helper is the outermost ADB method that issued the command
+	// (e.g. Tap, Brightness), Run if called directly.
+	Helper string
+	Cmd    string
+	// Input is true for commands that interact with the device the way a
+	// user would or change its state: input events, starting/stopping apps,
+	// changing settings, radios, display and battery overrides, content
+	// providers and files, privileged commands and batches.
+	Input bool
+	// Points are the coordinates of Tap, Drag and other touch input as
+	// passed to these methods (i.e.: before orientation translation).
+	Points []image.Point
+	// Script is the content of the script executed by Cmd, see RunScript.
+	Script string
+
+	Start       time.Time
+	Duration    time.Duration
+	StdoutBytes int
+	StderrBytes int
+	ExitCode    int
+	Err         error
+}
+
+// Middleware intercepts commands run by ADB.Run. It must call next to
+// actually run the command (which fills in the result fields of call) and
+// return its error, or return an error of its own to reject the command.
+// Middlewares may modify call.Cmd before calling next.
+type Middleware func(call *Call, next func() error) error
+
+// Use appends middlewares to the chain, the first registered one is the
+// outermost.
+func (adb *ADB) Use(m ...Middleware) { adb.middleware = append(adb.middleware, m...) }
+
+// Hooks creates a middleware from a before and after callback, both
+// optional.
+func Hooks(before, after func(*Call)) Middleware {
+	return func(call *Call, next func() error) error {
+		if before != nil {
+			before(call)
+		}
+		err := next()
+		if after != nil {
+			after(call)
+		}
+		return err
+	}
+}
+
+type counter struct {
+	w io.Writer
+	n int
+}
+
+func (c *counter) Write(b []byte) (int, error) {
+	c.n += len(b)
+	if c.w == nil {
+		return len(b), nil
+	}
+	return c.w.Write(b)
+}
+
+// helperName returns the outermost exported ADB method in the call stack.
+func helperName() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	const prefix = "github.com/frizinak/autodroid/adb.(*ADB)."
+	name := "Run"
+	for {
+		f, more := frames.Next()
+		if strings.HasPrefix(f.Function, prefix) {
+			m := strings.TrimPrefix(f.Function, prefix)
+			if m != "" && m[0] >= 'A' && m[0] <= 'Z' && !strings.Contains(m, ".") {
+				name = m
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return name
+}
+
+func (adb *ADB) intercept(cmd string, stdout, stderr io.Writer, run func(cmd string, stdout, stderr io.Writer) error) error {
+	if atomic.LoadInt32(&adb.streaming) != 0 {
+		adb.input, adb.points, adb.script = false, nil, ""
+		return &Error{Kind: ErrBusy, Err: fmt.Errorf("cannot run '%s'", cmd)}
+	}
+	if len(adb.middleware) == 0 {
+		return run(cmd, stdout, stderr)
+	}
+
+	call := &Call{Helper: helperName(), Cmd: cmd, Input: adb.input, Points: adb.points, Script: adb.script}
+	// commands run by middleware should not inherit these
+	adb.input, adb.points, adb.script = false, nil, ""
+	var chain func(i int) error
+	chain = func(i int) error {
+		if i < len(adb.middleware) {
+			return adb.middleware[i](call, func() error { return chain(i + 1) })
+		}
+		o, e := &counter{w: stdout}, &counter{w: stderr}
+		call.Start = time.Now()
+		err := run(call.Cmd, o, e)
+		call.Duration = time.Since(call.Start)
+		call.StdoutBytes, call.StderrBytes = o.n, e.n
+		call.Err = err
+		var ce *CmdError
+		if errors.As(err, &ce) {
+			call.ExitCode = ce.ExitCode
+		}
+		return err
+	}
+
+	return chain(0)
+}
+
+type auditEntry struct {
+	Time        time.Time `json:"time"`
+	Device      string    `json:"device"`
+	Helper      string    `json:"helper"`
+	Cmd         string    `json:"cmd"`
+	DurationMS  float64   `json:"duration_ms"`
+	StdoutBytes int       `json:"stdout_bytes"`
+	StderrBytes int       `json:"stderr_bytes"`
+	ExitCode    int       `json:"exit_code"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// AuditLog returns a middleware writing a json line per command to w.
+// Safe to share between clients.
+func AuditLog(w io.Writer, device string) Middleware {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return Hooks(nil, func(c *Call) {
+		e := auditEntry{
+			Time:        c.Start,
+			Device:      device,
+			Helper:      c.Helper,
+			Cmd:         c.Cmd,
+			DurationMS:  float64(c.Duration.Microseconds()) / 1000,
+			StdoutBytes: c.StdoutBytes,
+			StderrBytes: c.StderrBytes,
+			ExitCode:    c.ExitCode,
+		}
+		if c.Err != nil {
+			e.Error = c.Err.Error()
+		}
+		mu.Lock()
+		_ = enc.Encode(e)
+		mu.Unlock()
+	})
+}
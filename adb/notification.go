@@ -0,0 +1,227 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Notification struct {
+	Key     string
+	Package string
+	ID      int
+	Tag     string
+	Title   string
+	Text    string
+	Posted  time.Time
+	Actions []string
+}
+
+var (
+	notificationRecordRE = regexp.MustCompile(
+		`NotificationRecord\(0x[0-9a-f]+: pkg=(\S+) user=\S+ id=(-?\d+) tag=(\S*)(?: importance=\d+)? key=(\S+?)(?::|\s|$)`,
+	)
+	notificationExtraRE  = regexp.MustCompile(`^android\.(title|text)=\w+ \((.*)\)$`)
+	notificationActionRE = regexp.MustCompile(`^\[\d+\] "(.*)" -> `)
+	parcelRE             = regexp.MustCompile(`Result: Parcel\(\s*(?:0x[0-9a-f]+: )?([0-9a-f]{8})\b`)
+)
+
+// Notifications parses the posted notifications from
+// dumpsys notification --noredact.
+func (adb *ADB) Notifications() ([]Notification, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("dumpsys notification --noredact", buf, nil); err != nil {
+		return nil, err
+	}
+
+	list := make([]Notification, 0)
+	var cur *Notification
+	var inList, inActions bool
+	s := bufio.NewScanner(buf)
+	s.Buffer(make([]byte, 0, 1024*64), 1024*1024)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "Notification List:" {
+			inList = true
+			continue
+		}
+		if !inList {
+			continue
+		}
+		if l == "Enqueued Notification List:" ||
+			strings.HasPrefix(l, "Snoozed") ||
+			strings.HasPrefix(l, "mArchive") {
+			break
+		}
+		if res := notificationRecordRE.FindStringSubmatch(l); len(res) == 5 {
+			id, _ := strconv.Atoi(res[2])
+			tag := res[3]
+			if tag == "null" {
+				tag = ""
+			}
+			list = append(list, Notification{Package: res[1], ID: id, Tag: tag, Key: res[4]})
+			cur = &list[len(list)-1]
+			inActions = false
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		switch {
+		case inActions && l == "}":
+			inActions = false
+		case inActions:
+			if res := notificationActionRE.FindStringSubmatch(l); len(res) == 2 {
+				cur.Actions = append(cur.Actions, res[1])
+			}
+		case l == "actions={":
+			inActions = true
+		case strings.HasPrefix(l, "postTime=") || strings.HasPrefix(l, "mCreationTimeMs="):
+			v := l[strings.IndexByte(l, '=')+1:]
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil && ms > 0 {
+				cur.Posted = time.UnixMilli(ms)
+			}
+		case strings.HasPrefix(l, "when=") && cur.Posted.IsZero():
+			if ms, err := strconv.ParseInt(strings.TrimPrefix(l, "when="), 10, 64); err == nil && ms > 0 {
+				cur.Posted = time.UnixMilli(ms)
+			}
+		default:
+			res := notificationExtraRE.FindStringSubmatch(l)
+			if len(res) != 3 {
+				continue
+			}
+			switch res[1] {
+			case "title":
+				cur.Title = res[2]
+			case "text":
+				cur.Text = res[2]
+			}
+		}
+	}
+
+	return list, s.Err()
+}
+
+// WaitNotification polls Notifications until match returns true or
+// timeout expires.
+func (adb *ADB) WaitNotification(match func(Notification) bool, timeout time.Duration) (Notification, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		list, err := adb.Notifications()
+		if err != nil {
+			return Notification{}, err
+		}
+		for _, n := range list {
+			if match(n) {
+				return n, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return Notification{}, ErrTimeout
+		}
+		time.Sleep(time.Millisecond * 500)
+	}
+}
+
+func (adb *ADB) ExpandNotifications() error {
+	return adb.runInput("cmd statusbar expand-notifications >/dev/null 2>&1")
+}
+
+func (adb *ADB) ExpandSettings() error {
+	return adb.runInput("cmd statusbar expand-settings >/dev/null 2>&1")
+}
+
+func (adb *ADB) CollapseNotifications() error {
+	return adb.runInput("cmd statusbar collapse >/dev/null 2>&1")
+}
+
+// ErrNodeNotFound is returned when no UI node in the notification shade
+// matches.
+var ErrNodeNotFound = errors.New("ui node not found")
+
+// ClearNotifications cancels all clearable notifications through the
+// notification service, falling back to tapping the shade's 'clear all'
+// button, and collapses the shade.
+func (adb *ADB) ClearNotifications() error {
+	if err := adb.cancelAllNotifications(); err == nil {
+		return adb.CollapseNotifications()
+	}
+
+	n, err := adb.findInShade(func(n *UINode) bool {
+		return strings.HasSuffix(n.ResourceID, ":id/dismiss_text") ||
+			strings.HasSuffix(n.ResourceID, ":id/clear_all") ||
+			strings.EqualFold(n.Text, "clear all")
+	})
+	if err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			// nothing to clear
+			return adb.CollapseNotifications()
+		}
+		return err
+	}
+	if err := adb.TapNode(n); err != nil {
+		return err
+	}
+	return adb.CollapseNotifications()
+}
+
+// cancelAllNotifications calls INotificationManager.cancelAllNotifications.
+// Its transaction code (1) differs between android versions and the shell
+// user is usually not allowed to call it, service call exits 0 regardless
+// so the reply parcel is checked for an exception.
+func (adb *ADB) cancelAllNotifications() error {
+	buf := bytes.NewBuffer(nil)
+	if err := adb.runInputTo("service call notification 1 2>&1", buf, nil); err != nil {
+		return err
+	}
+	return parcelError(buf.String())
+}
+
+// parcelError returns an error if the output of service call is not a
+// reply parcel without exception.
+func parcelError(output string) error {
+	output = strings.TrimSpace(output)
+	res := parcelRE.FindStringSubmatch(output)
+	if len(res) != 2 {
+		return fmt.Errorf("unexpected service call output '%s'", output)
+	}
+	if res[1] != "00000000" {
+		return fmt.Errorf("service call exception '%s'", output)
+	}
+	return nil
+}
+
+// OpenNotification opens the shade and taps the notification's title.
+func (adb *ADB) OpenNotification(notif Notification) error {
+	if notif.Title == "" {
+		return errors.New("notification has no title")
+	}
+	n, err := adb.findInShade(func(n *UINode) bool { return n.Text == notif.Title })
+	if err != nil {
+		_ = adb.CollapseNotifications()
+		return err
+	}
+	return adb.TapNode(n)
+}
+
+func (adb *ADB) findInShade(match func(*UINode) bool) (*UINode, error) {
+	if err := adb.ExpandNotifications(); err != nil {
+		return nil, err
+	}
+	time.Sleep(time.Millisecond * 500)
+	root, err := adb.UIHierarchy()
+	if err != nil {
+		return nil, err
+	}
+	l := root.Find(match)
+	if len(l) == 0 {
+		return nil, ErrNodeNotFound
+	}
+	return l[0], nil
+}
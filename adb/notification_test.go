@@ -0,0 +1,137 @@
+package adb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParcelError(t *testing.T) {
+	tests := []struct {
+		output string
+		err    bool
+	}{
+		{"Result: Parcel(00000000    '....')", false},
+		{"Result: Parcel(00000000 00000001   '........')", false},
+		{
+			"Result: Parcel(\n" +
+				"  0x00000000: 00000000 00000002 00000001 00000000 '................'\n" +
+				"  0x00000010: 00000000                            '....            ')",
+			false,
+		},
+		{
+			"Result: Parcel(\n" +
+				"  0x00000000: ffffffff 0000004f 00650076 00750073 '....O...S.e.c.u.'\n" +
+				"  0x00000010: 00690072 00790074 00780045 00650063 'r.i.t.y.E.x.c.e.')",
+			true,
+		},
+		{"Result: Parcel(ffffffff 00000038 004e0020 00740020 '....8... .N. .t.')", true},
+		{"service: Service notification does not exist", true},
+		{"", true},
+	}
+	for _, test := range tests {
+		err := parcelError(test.output)
+		if (err != nil) != test.err {
+			t.Errorf("%q: expected error %t, got %v", test.output, test.err, err)
+		}
+	}
+}
+
+// captured on a pixel 4a, android 12, trimmed
+const notificationDump = `Current Notification Manager state:
+  Notification List:
+    NotificationRecord(0x0c1b2d3e: pkg=com.whatsapp user=UserHandle{0} id=1 tag=null importance=4 key=0|com.whatsapp|1|null|10123: Notification(channel=individual_chat_defaults_3 shortcut=null contentView=null vibrate=null sound=null defaults=0x0 flags=0x19 color=0xff075e54 category=msg groupKey=group_key_messages actions=2 vis=PRIVATE publicVersion=Notification(channel=null shortcut=null contentView=null vibrate=null sound=null defaults=0x0 flags=0x0 color=0xff075e54 vis=PRIVATE)))
+      uid=10123 userId=0
+      opPkg=com.whatsapp
+      icon=Icon(typ=RESOURCE pkg=com.whatsapp id=0x7f080a2b)
+      flags=0x19
+      pri=1
+      key=0|com.whatsapp|1|null|10123
+      seen=false
+      groupKey=0|com.whatsapp|g:group_key_messages
+      notification=
+        fullscreenIntent=null
+        contentIntent=PendingIntent{f1e2d3c: PendingIntentRecord{a9b8c7d com.whatsapp startActivity}}
+        deleteIntent=PendingIntent{3c4d5e6: PendingIntentRecord{7a8b9c0 com.whatsapp broadcastIntent}}
+        number=0
+        groupAlertBehavior=2
+        when=1697712345678
+        tickerText=null
+        contentView=null
+        bigContentView=null
+        headsUpContentView=null
+        color=0xff075e54
+        actions={
+          [0] "Reply" -> PendingIntent{5f3a2b1: PendingIntentRecord{0d1e2f3 com.whatsapp broadcastIntent}}
+          [1] "Mark as read" -> PendingIntent{6a4b3c2: PendingIntentRecord{4f5a6b7 com.whatsapp broadcastIntent}}
+        }
+        style=android.app.Notification$MessagingStyle
+        extras={
+          android.title=String (Alice)
+          android.reduced.images=Boolean (true)
+          android.subText=null
+          android.template=String (android.app.Notification$MessagingStyle)
+          android.text=String (See you at 8? (bring snacks))
+          android.messages=Parcelable[] (1)
+        }
+      stats=SingleNotificationStats{posttimeElapsedMs=5120331, posttimeToFirstClickMs=-1}
+      mContactAffinity=0.0
+      mRecentlyIntrusive=false
+      mPackagePriority=0
+      mPackageVisibility=-1000
+      mSystemImportance=4
+      mCreationTimeMs=1697712345690
+      mVisibleSinceMs=0
+      mUpdateTimeMs=1697712345690
+      mInterruptionTimeMs=1697712345690
+    NotificationRecord(0x04a5b6c7: pkg=android user=UserHandle{-1} id=17041018 tag=usb_charging importance=1 key=-1|android|17041018|usb_charging|1000: Notification(channel=USB shortcut=null contentView=null vibrate=null sound=null defaults=0x0 flags=0x2 color=0xff1a73e8 vis=PUBLIC))
+      uid=1000 userId=-1
+      opPkg=android
+      notification=
+        when=1697700000000
+        extras={
+          android.title=SpannableString (Charging this device via USB)
+          android.text=String (Tap for more options.)
+        }
+      mContactAffinity=0.0
+  Enqueued Notification List:
+    NotificationRecord(0x0e1f2a3b: pkg=com.example.game user=UserHandle{0} id=3 tag=null importance=3 key=0|com.example.game|3|null|10211: Notification(channel=rewards))
+        extras={
+          android.title=String (Not posted yet)
+        }
+  mArchive:
+`
+
+func TestNotifications(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.Handle("dumpsys", fakeOutput(notificationDump))
+	c := fakeClient(t, d)
+
+	list, err := c.Notifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Notification{
+		{
+			Package: "com.whatsapp",
+			ID:      1,
+			Key:     "0|com.whatsapp|1|null|10123",
+			Title:   "Alice",
+			Text:    "See you at 8? (bring snacks)",
+			Posted:  time.UnixMilli(1697712345690),
+			Actions: []string{"Reply", "Mark as read"},
+		},
+		{
+			Package: "android",
+			ID:      17041018,
+			Tag:     "usb_charging",
+			Key:     "-1|android|17041018|usb_charging|1000",
+			Title:   "Charging this device via USB",
+			Text:    "Tap for more options.",
+			Posted:  time.UnixMilli(1697700000000),
+		},
+	}
+	if !reflect.DeepEqual(list, exp) {
+		t.Errorf("expected\n%+v\ngot\n%+v", exp, list)
+	}
+}
@@ -0,0 +1,175 @@
+package adb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"time"
+)
+
+type Wakefulness string
+
+const (
+	WakefulnessUnknown  Wakefulness = ""
+	WakefulnessAwake    Wakefulness = "Awake"
+	WakefulnessAsleep   Wakefulness = "Asleep"
+	WakefulnessDozing   Wakefulness = "Dozing"
+	WakefulnessDreaming Wakefulness = "Dreaming"
+)
+
+type ScreenState struct {
+	Wakefulness Wakefulness
+	Locked      bool
+}
+
+// On reports whether the display is awake and usable for input.
+func (s ScreenState) On() bool { return s.Wakefulness == WakefulnessAwake }
+
+var (
+	wakefulnessRE = regexp.MustCompile(`mWakefulness=(\w+)`)
+	keyguardRE    = regexp.MustCompile(
+		`(?:mShowingLockscreen|mDreamingLockscreen|isStatusBarKeyguard|mKeyguardShowing|KeyguardShowing)=true`,
+	)
+)
+
+func (adb *ADB) ScreenState() (ScreenState, error) {
+	var s ScreenState
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run("dumpsys power 2>&1 | grep 'mWakefulness=' | head -n 1", buf, nil)
+	if err != nil {
+		return s, err
+	}
+	res := wakefulnessRE.FindStringSubmatch(buf.String())
+	if len(res) == 2 {
+		s.Wakefulness = Wakefulness(res[1])
+	}
+
+	v, err := adb.grep("dumpsys window 2>&1 | grep -E 'Lockscreen|Keyguard' | grep -E '=true'")
+	if err != nil {
+		return s, err
+	}
+	s.Locked = keyguardRE.MatchString(v)
+
+	return s, nil
+}
+
+func (adb *ADB) Wake() error {
+	return adb.KeyEvent("KEYCODE_WAKEUP")
+}
+
+func (adb *ADB) Sleep() error {
+	return adb.KeyEvent("KEYCODE_SLEEP")
+}
+
+// StayAwake keeps the device awake while plugged in (any power source).
+func (adb *ADB) StayAwake(on bool) error {
+	v := "false"
+	if on {
+		v = "true"
+	}
+	return adb.runInput(fmt.Sprintf("svc power stayon %s >/dev/null 2>&1", v))
+}
+
+// Unlock is a keyguard unlock method, see UnlockSwipe, UnlockPIN and
+// UnlockPattern.
+type Unlock func(adb *ADB) error
+
+// UnlockSwipe swipes up from the bottom to the center of the display to
+// dismiss an insecure keyguard.
+func UnlockSwipe() Unlock {
+	return func(adb *ADB) error {
+		size, err := adb.DisplaySize()
+		if err != nil {
+			return err
+		}
+		if _, ok := adb.Orientation(); !ok {
+			// Drag takes rotated coordinates
+			rot, err := adb.Rotation()
+			if err != nil {
+				return err
+			}
+			if rot.Landscape() {
+				size = image.Pt(size.Y, size.X)
+			}
+		}
+		x := size.X / 2
+		return adb.Drag(x, size.Y*9/10, x, size.Y/2, time.Millisecond*300)
+	}
+}
+
+// UnlockPIN enters pin (or password) in the keyguard bouncer.
+func UnlockPIN(pin string) Unlock {
+	return func(adb *ADB) error {
+		if err := adb.Text(pin); err != nil {
+			return err
+		}
+		return adb.KeyEvent("KEYCODE_ENTER")
+	}
+}
+
+// UnlockPattern draws a pattern through the given screen coordinates.
+// Requires android 11+ (input motionevent).
+func UnlockPattern(points []image.Point, step time.Duration) Unlock {
+	return func(adb *ADB) error {
+		if len(points) == 0 {
+			return errors.New("empty unlock pattern")
+		}
+		for i, p := range points {
+			x, y := adb.xy(p.X, p.Y)
+			action := "MOVE"
+			if i == 0 {
+				action = "DOWN"
+			}
+			err := adb.runInput(
+				fmt.Sprintf("input motionevent %s %d %d >/dev/null 2>&1", action, x, y),
+				p,
+			)
+			if err != nil {
+				return err
+			}
+			time.Sleep(step)
+		}
+		l := points[len(points)-1]
+		x, y := adb.xy(l.X, l.Y)
+		return adb.runInput(fmt.Sprintf("input motionevent UP %d %d >/dev/null 2>&1", x, y), l)
+	}
+}
+
+var ErrLocked = errors.New("device is still locked")
+
+// Unlock wakes the device and unlocks the keyguard using the given method.
+func (adb *ADB) Unlock(method Unlock) error {
+	s, err := adb.ScreenState()
+	if err != nil {
+		return err
+	}
+	if !s.On() {
+		if err := adb.Wake(); err != nil {
+			return err
+		}
+		time.Sleep(time.Millisecond * 300)
+	}
+	if !s.Locked {
+		return nil
+	}
+
+	if err := adb.runInput("wm dismiss-keyguard >/dev/null 2>&1"); err != nil {
+		return err
+	}
+	time.Sleep(time.Millisecond * 300)
+	if err := method(adb); err != nil {
+		return err
+	}
+	time.Sleep(time.Millisecond * 500)
+
+	s, err = adb.ScreenState()
+	if err != nil {
+		return err
+	}
+	if s.Locked {
+		return ErrLocked
+	}
+	return nil
+}
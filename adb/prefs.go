@@ -0,0 +1,149 @@
+package adb
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// SharedPreferences maps keys to values of type
+// string, int32, int64, float32, bool or []string.
+type SharedPreferences map[string]interface{}
+
+func (p SharedPreferences) String(key string) (string, bool) {
+	v, ok := p[key].(string)
+	return v, ok
+}
+
+func (p SharedPreferences) Int(key string) (int32, bool) {
+	v, ok := p[key].(int32)
+	return v, ok
+}
+
+func (p SharedPreferences) Long(key string) (int64, bool) {
+	v, ok := p[key].(int64)
+	return v, ok
+}
+
+func (p SharedPreferences) Float(key string) (float32, bool) {
+	v, ok := p[key].(float32)
+	return v, ok
+}
+
+func (p SharedPreferences) Bool(key string) (bool, bool) {
+	v, ok := p[key].(bool)
+	return v, ok
+}
+
+func (p SharedPreferences) StringSet(key string) ([]string, bool) {
+	v, ok := p[key].([]string)
+	return v, ok
+}
+
+type xmlPrefs struct {
+	XMLName xml.Name       `xml:"map"`
+	Entries []xmlPrefEntry `xml:",any"`
+}
+
+type xmlPrefEntry struct {
+	XMLName xml.Name
+	Name    string   `xml:"name,attr"`
+	Value   *string  `xml:"value,attr"`
+	Text    string   `xml:",chardata"`
+	Strings []string `xml:"string"`
+}
+
+func ParseSharedPreferences(d []byte) (SharedPreferences, error) {
+	var x xmlPrefs
+	if err := xml.Unmarshal(d, &x); err != nil {
+		return nil, err
+	}
+
+	p := make(SharedPreferences, len(x.Entries))
+	for _, e := range x.Entries {
+		value := ""
+		if e.Value != nil {
+			value = *e.Value
+		}
+		var err error
+		switch e.XMLName.Local {
+		case "string":
+			p[e.Name] = e.Text
+		case "int":
+			var v int64
+			v, err = strconv.ParseInt(value, 10, 32)
+			p[e.Name] = int32(v)
+		case "long":
+			var v int64
+			v, err = strconv.ParseInt(value, 10, 64)
+			p[e.Name] = v
+		case "float":
+			var v float64
+			v, err = strconv.ParseFloat(value, 32)
+			p[e.Name] = float32(v)
+		case "boolean":
+			p[e.Name] = value == "true"
+		case "set":
+			p[e.Name] = append([]string{}, e.Strings...)
+		case "null":
+		default:
+			err = fmt.Errorf("unsupported preference type '%s'", e.XMLName.Local)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("preference '%s': %w", e.Name, err)
+		}
+	}
+
+	return p, nil
+}
+
+// Marshal encodes the preferences in the format used by android.
+func (p SharedPreferences) Marshal() ([]byte, error) {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	x := xmlPrefs{Entries: make([]xmlPrefEntry, 0, len(keys))}
+	for _, k := range keys {
+		e := xmlPrefEntry{Name: k}
+		attr := func(typ, v string) {
+			e.XMLName.Local = typ
+			e.Value = &v
+		}
+		switch v := p[k].(type) {
+		case string:
+			e.XMLName.Local = "string"
+			e.Text = v
+		case int32:
+			attr("int", strconv.FormatInt(int64(v), 10))
+		case int:
+			attr("int", strconv.Itoa(v))
+		case int64:
+			attr("long", strconv.FormatInt(v, 10))
+		case float32:
+			attr("float", strconv.FormatFloat(float64(v), 'f', -1, 32))
+		case bool:
+			attr("boolean", strconv.FormatBool(v))
+		case []string:
+			e.XMLName.Local = "set"
+			e.Strings = v
+		default:
+			return nil, fmt.Errorf("preference '%s': unsupported type %T", k, v)
+		}
+		x.Entries = append(x.Entries, e)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("<?xml version='1.0' encoding='utf-8' standalone='yes' ?>\n")
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "    ")
+	if err := enc.Encode(x); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package adb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DeviceProfile is a bundle of settings that make a device more suitable
+// for automation. Zero values leave the respective settings untouched.
+type DeviceProfile struct {
+	// DisableAnimations sets the window, transition and animator duration
+	// scales to 0.
+	DisableAnimations bool
+
+	// LockRotation disables auto rotation and forces UserRotation
+	// (0: 0°, 1: 90°, 2: 180°, 3: 270°).
+	LockRotation bool
+	UserRotation int
+
+	// StayOnWhilePlugged keeps the screen on while connected to any
+	// power source.
+	StayOnWhilePlugged bool
+
+	ScreenTimeout time.Duration
+
+	ShowTouches     bool
+	PointerLocation bool
+
+	// StateFile, if set, is where original values are persisted so a
+	// subsequent Apply or Revert can restore them after a crash.
+	StateFile string
+}
+
+// AutomationProfile returns a DeviceProfile with all automation friendly
+// options enabled.
+func AutomationProfile(stateFile string) DeviceProfile {
+	return DeviceProfile{
+		DisableAnimations:  true,
+		LockRotation:       true,
+		StayOnWhilePlugged: true,
+		ScreenTimeout:      time.Minute * 30,
+		ShowTouches:        true,
+		PointerLocation:    true,
+		StateFile:          stateFile,
+	}
+}
+
+type settingValue struct {
+	SettingKey
+	Value string
+}
+
+func (p DeviceProfile) settings() []settingValue {
+	l := make([]settingValue, 0, 9)
+	add := func(ns Namespace, key, value string) {
+		l = append(l, settingValue{SettingKey{ns, key}, value})
+	}
+	if p.DisableAnimations {
+		add(Global, "window_animation_scale", "0")
+		add(Global, "transition_animation_scale", "0")
+		add(Global, "animator_duration_scale", "0")
+	}
+	if p.LockRotation {
+		add(System, "accelerometer_rotation", "0")
+		add(System, "user_rotation", strconv.Itoa(p.UserRotation))
+	}
+	if p.StayOnWhilePlugged {
+		// BatteryManager.BATTERY_PLUGGED_AC|USB|WIRELESS
+		add(Global, "stay_on_while_plugged_in", "7")
+	}
+	if p.ScreenTimeout > 0 {
+		add(System, "screen_off_timeout", strconv.FormatInt(p.ScreenTimeout.Milliseconds(), 10))
+	}
+	if p.ShowTouches {
+		add(SettingShowTouches.Namespace, SettingShowTouches.Key, "1")
+	}
+	if p.PointerLocation {
+		add(System, "pointer_location", "1")
+	}
+	return l
+}
+
+type profileState struct {
+	Serial   string         `json:"serial"`
+	Settings []settingValue `json:"settings"`
+}
+
+// AppliedProfile is the result of DeviceProfile.Apply.
+type AppliedProfile struct {
+	adb  *ADB
+	snap *SettingsSnapshot
+	file string
+}
+
+// Apply reverts any state left behind by a previous crashed run and applies
+// the profile.
+func (p DeviceProfile) Apply(adb *ADB) (*AppliedProfile, error) {
+	if p.LockRotation && (p.UserRotation < 0 || p.UserRotation > 3) {
+		return nil, fmt.Errorf("invalid user rotation %d, must be 0-3", p.UserRotation)
+	}
+	if err := p.Revert(adb); err != nil {
+		return nil, err
+	}
+
+	list := p.settings()
+	keys := make([]SettingKey, len(list))
+	for i := range list {
+		keys[i] = list[i].SettingKey
+	}
+
+	snap, err := adb.Snapshot(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &AppliedProfile{adb: adb, snap: snap, file: p.StateFile}
+	if err := a.persist(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range list {
+		if err := adb.SetSetting(s.Namespace, s.Key, s.Value); err != nil {
+			_ = a.Revert()
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Revert restores the originals persisted in StateFile, if any.
+func (p DeviceProfile) Revert(adb *ADB) error {
+	if p.StateFile == "" {
+		return nil
+	}
+	f, err := os.Open(p.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var state profileState
+	err = json.NewDecoder(f).Decode(&state)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("corrupt profile state file %s: %w", p.StateFile, err)
+	}
+	if state.Serial != adb.Serial() {
+		return fmt.Errorf(
+			"profile state file %s belongs to device '%s' not '%s'",
+			p.StateFile,
+			state.Serial,
+			adb.Serial(),
+		)
+	}
+
+	snap := &SettingsSnapshot{adb: adb, orig: make(map[SettingKey]string)}
+	for _, s := range state.Settings {
+		snap.list = append(snap.list, s.SettingKey)
+		snap.orig[s.SettingKey] = s.Value
+	}
+
+	a := &AppliedProfile{adb: adb, snap: snap, file: p.StateFile}
+	return a.Revert()
+}
+
+func (a *AppliedProfile) persist() error {
+	if a.file == "" {
+		return nil
+	}
+	state := profileState{Serial: a.adb.Serial()}
+	for _, k := range a.snap.list {
+		state.Settings = append(state.Settings, settingValue{k, a.snap.orig[k]})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.file), 0o755); err != nil {
+		return err
+	}
+	tmp := a.file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(state); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.file)
+}
+
+// Revert restores all original settings and removes the state file.
+func (a *AppliedProfile) Revert() error {
+	if err := a.snap.Restore(); err != nil {
+		return err
+	}
+	if a.file == "" {
+		return nil
+	}
+	if err := os.Remove(a.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (a *AppliedProfile) Close() error { return a.Revert() }
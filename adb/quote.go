@@ -0,0 +1,8 @@
+package adb
+
+import "strings"
+
+// Quote returns s single quoted for use as a single shell argument.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
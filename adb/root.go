@@ -0,0 +1,122 @@
+package adb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type RootMethod int
+
+const (
+	// RootNone opens a regular shell.
+	RootNone RootMethod = iota
+	// RootAdbd restarts adbd as root (adb root), userdebug/eng builds only.
+	RootAdbd
+	// RootSu opens the shell session through su.
+	RootSu
+)
+
+var ErrNoRoot = errors.New("root not available")
+
+// SetRoot sets how Init opens the shell session, takes effect on the next
+// Init.
+func (adb *ADB) SetRoot(m RootMethod) {
+	adb.root = m
+	adb.uid = -1
+	adb.su = ""
+}
+
+func (adb *ADB) initRoot() (args []string, err error) {
+	switch adb.root {
+	case RootAdbd:
+		out, err := adb.command("root").CombinedOutput()
+		o := strings.TrimSpace(string(out))
+		if err != nil || strings.Contains(o, "cannot run as root") {
+			return nil, fmt.Errorf("%w: %s", ErrNoRoot, o)
+		}
+		if err := adb.command("wait-for-device").Run(); err != nil {
+			return nil, err
+		}
+	case RootSu:
+		return []string{"su"}, nil
+	}
+	return nil, nil
+}
+
+// UID returns the uid of the shell session.
+func (adb *ADB) UID() (int, error) {
+	if adb.uid >= 0 {
+		return adb.uid, nil
+	}
+	v, err := adb.grep("id -u")
+	if err != nil {
+		return 0, err
+	}
+	var uid int
+	if _, err := fmt.Sscanf(v, "%d", &uid); err != nil {
+		return 0, err
+	}
+	adb.uid = uid
+	return uid, nil
+}
+
+// suPrefix detects the su syntax (SuperSU/Magisk or AOSP).
+func (adb *ADB) suPrefix() (string, error) {
+	if adb.su != "" {
+		return adb.su, nil
+	}
+	for _, prefix := range []string{"su -c", "su 0 sh -c"} {
+		v, err := adb.grep(fmt.Sprintf("%s %s 2>/dev/null </dev/null", prefix, Quote("id -u")))
+		if err != nil {
+			var ce *CmdError
+			if errors.As(err, &ce) {
+				continue
+			}
+			return "", err
+		}
+		if v == "0" {
+			adb.su = prefix
+			return prefix, nil
+		}
+	}
+	return "", ErrNoRoot
+}
+
+// HasRoot reports whether privileged commands can be run, either because
+// the session itself runs as root or because su is available.
+func (adb *ADB) HasRoot() (bool, error) {
+	uid, err := adb.UID()
+	if err != nil || uid == 0 {
+		return uid == 0, err
+	}
+	_, err = adb.suPrefix()
+	if errors.Is(err, ErrNoRoot) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RunPrivileged runs cmd as root, wrapping it in su if the session is not
+// root itself.
+func (adb *ADB) RunPrivileged(cmd string, stdout, stderr io.Writer) error {
+	cmd, err := adb.privileged(cmd)
+	if err != nil {
+		return err
+	}
+	return adb.runInputTo(cmd, stdout, stderr)
+}
+
+// privileged wraps cmd in su if needed.
+func (adb *ADB) privileged(cmd string) (string, error) {
+	uid, err := adb.UID()
+	if err != nil || uid == 0 {
+		return cmd, err
+	}
+	prefix, err := adb.suPrefix()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s </dev/null", prefix, Quote(cmd)), nil
+}
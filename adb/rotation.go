@@ -0,0 +1,174 @@
+package adb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+)
+
+// Rotation of the display relative to its natural orientation, in quarter
+// turns counter clockwise (Surface.ROTATION_*).
+type Rotation int
+
+const (
+	Rotation0 Rotation = iota
+	Rotation90
+	Rotation180
+	Rotation270
+)
+
+func (r Rotation) norm() Rotation { return ((r % 4) + 4) % 4 }
+
+// Landscape reports whether width and height are swapped compared to the
+// natural orientation.
+func (r Rotation) Landscape() bool { return r.norm()%2 == 1 }
+
+func (r Rotation) String() string { return fmt.Sprintf("%d°", int(r.norm())*90) }
+
+// Orientation maps coordinates between the natural orientation of a
+// display and its current rotation.
+type Orientation struct {
+	// Natural display size (i.e.: portrait for most phones).
+	Natural  image.Point
+	Rotation Rotation
+}
+
+// Size returns the display size in the current rotation.
+func (o Orientation) Size() image.Point {
+	if o.Rotation.Landscape() {
+		return image.Pt(o.Natural.Y, o.Natural.X)
+	}
+	return o.Natural
+}
+
+// Point converts a pixel in natural coordinates to current coordinates.
+func (o Orientation) Point(p image.Point) image.Point {
+	return o.point(p, o.Natural.X-1, o.Natural.Y-1)
+}
+
+// Inverse converts a pixel in current coordinates to natural coordinates.
+func (o Orientation) Inverse(p image.Point) image.Point {
+	return o.inverse(p, o.Natural.X-1, o.Natural.Y-1)
+}
+
+// point rotates p within a w by h space.
+func (o Orientation) point(p image.Point, w, h int) image.Point {
+	switch o.Rotation.norm() {
+	case Rotation90:
+		return image.Pt(p.Y, w-p.X)
+	case Rotation180:
+		return image.Pt(w-p.X, h-p.Y)
+	case Rotation270:
+		return image.Pt(h-p.Y, p.X)
+	}
+	return p
+}
+
+func (o Orientation) inverse(p image.Point, w, h int) image.Point {
+	switch o.Rotation.norm() {
+	case Rotation90:
+		return image.Pt(w-p.Y, p.X)
+	case Rotation180:
+		return image.Pt(w-p.X, h-p.Y)
+	case Rotation270:
+		return image.Pt(p.Y, h-p.X)
+	}
+	return p
+}
+
+// Rect converts a rectangle in natural coordinates to current coordinates.
+// Unlike Point, the (exclusive) edges of the rectangle are mapped.
+func (o Orientation) Rect(r image.Rectangle) image.Rectangle {
+	w, h := o.Natural.X, o.Natural.Y
+	return image.Rectangle{o.point(r.Min, w, h), o.point(r.Max, w, h)}.Canon()
+}
+
+// InverseRect converts a rectangle in current coordinates to natural
+// coordinates.
+func (o Orientation) InverseRect(r image.Rectangle) image.Rectangle {
+	w, h := o.Natural.X, o.Natural.Y
+	return image.Rectangle{o.inverse(r.Min, w, h), o.inverse(r.Max, w, h)}.Canon()
+}
+
+var rotationRE = regexp.MustCompile(`(?:SurfaceOrientation: |orientation=)(\d)`)
+
+func (adb *ADB) Rotation() (Rotation, error) {
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run(
+		"dumpsys input 2>&1 | grep -E 'SurfaceOrientation|orientation=' | head -n 1",
+		buf,
+		nil,
+	)
+	if err != nil {
+		return 0, err
+	}
+	res := rotationRE.FindStringSubmatch(buf.String())
+	if len(res) != 2 {
+		return 0, errors.New("could not determine display rotation")
+	}
+	r, err := strconv.Atoi(res[1])
+	return Rotation(r), err
+}
+
+// SetRotation disables auto rotation and rotates the display.
+func (adb *ADB) SetRotation(r Rotation) error {
+	if err := adb.SetSetting(System, "accelerometer_rotation", "0"); err != nil {
+		return err
+	}
+	cmd := setSettingCmd(System, "user_rotation", strconv.Itoa(int(r.norm())))
+	return adb.runInputApply(cmd, func() {
+		if adb.orient != nil {
+			adb.orient.Rotation = r.norm()
+		}
+	})
+}
+
+// SetOrientationAware enables or disables translating coordinates passed
+// to the input methods (Tap, Drag, ...) from the natural orientation to
+// the current rotation.
+// Call UpdateOrientation when the rotation might have changed.
+func (adb *ADB) SetOrientationAware(on bool) error {
+	if !on {
+		adb.orient = nil
+		return nil
+	}
+	size, err := adb.DisplaySize()
+	if err != nil {
+		return err
+	}
+	adb.orient = &Orientation{Natural: size}
+	return adb.UpdateOrientation()
+}
+
+// UpdateOrientation refreshes the current rotation used by
+// SetOrientationAware.
+func (adb *ADB) UpdateOrientation() error {
+	if adb.orient == nil {
+		return nil
+	}
+	r, err := adb.Rotation()
+	if err != nil {
+		return err
+	}
+	adb.orient.Rotation = r
+	return nil
+}
+
+// Orientation returns the orientation used to translate input coordinates.
+func (adb *ADB) Orientation() (Orientation, bool) {
+	if adb.orient == nil {
+		return Orientation{}, false
+	}
+	return *adb.orient, true
+}
+
+func (adb *ADB) xy(x, y int) (int, int) {
+	if adb.orient == nil {
+		return x, y
+	}
+	p := adb.orient.Point(image.Pt(x, y))
+	return p.X, p.Y
+}
@@ -0,0 +1,138 @@
+package adb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RunAs runs commands as a debuggable package, relative paths are
+// resolved against the package's data directory.
+type RunAs struct {
+	adb *ADB
+	pkg string
+}
+
+func (adb *ADB) RunAs(pkg string) *RunAs {
+	return &RunAs{adb: adb, pkg: pkg}
+}
+
+func (r *RunAs) Package() string { return r.pkg }
+
+// Run runs cmd in a shell as the package user.
+func (r *RunAs) Run(cmd string, stdout, stderr io.Writer) error {
+	return r.adb.Run(r.command(cmd), stdout, stderr)
+}
+
+func (r *RunAs) command(cmd string) string {
+	return fmt.Sprintf("run-as %s sh -c %s", Quote(r.pkg), Quote(cmd))
+}
+
+func (r *RunAs) output(cmd string) ([]byte, error) {
+	return r.exec(cmd, r.Run)
+}
+
+// modify runs a command that changes the package's files, see
+// Call.Input.
+func (r *RunAs) modify(cmd string) error {
+	_, err := r.exec(cmd, func(cmd string, stdout, stderr io.Writer) error {
+		return r.adb.runInputTo(r.command(cmd), stdout, stderr)
+	})
+	return err
+}
+
+func (r *RunAs) exec(cmd string, run func(cmd string, stdout, stderr io.Writer) error) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := run(cmd, buf, stderr)
+	if err != nil && stderr.Len() != 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return buf.Bytes(), err
+}
+
+// DataDir returns the absolute path of the package's data directory.
+func (r *RunAs) DataDir() (string, error) {
+	d, err := r.output("pwd")
+	return strings.TrimSpace(string(d)), err
+}
+
+func (r *RunAs) ReadFile(path string) ([]byte, error) {
+	d, err := r.output(fmt.Sprintf("base64 < %s", Quote(path)))
+	if err != nil {
+		return nil, err
+	}
+	d = bytes.Join(bytes.Fields(d), nil)
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(d)))
+	n, err := base64.StdEncoding.Decode(out, d)
+	return out[:n], err
+}
+
+// WriteFile creates or truncates path and writes data to it.
+func (r *RunAs) WriteFile(path string, data []byte) error {
+	return writeFile(path, data, r.modify)
+}
+
+func (r *RunAs) Remove(path string) error {
+	return r.modify(fmt.Sprintf("rm -rf %s", Quote(path)))
+}
+
+func (r *RunAs) MkdirAll(path string) error {
+	return r.modify(fmt.Sprintf("mkdir -p %s", Quote(path)))
+}
+
+// List returns the names of the entries in dir.
+func (r *RunAs) List(dir string) ([]string, error) {
+	d, err := r.output(fmt.Sprintf("ls -1 %s", Quote(dir)))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0)
+	for _, l := range strings.Split(string(d), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			list = append(list, l)
+		}
+	}
+	return list, nil
+}
+
+func prefsPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "/") {
+		return "", errors.New("invalid shared preferences name")
+	}
+	return "shared_prefs/" + name + ".xml", nil
+}
+
+// Preferences reads shared_prefs/<name>.xml.
+func (r *RunAs) Preferences(name string) (SharedPreferences, error) {
+	p, err := prefsPath(name)
+	if err != nil {
+		return nil, err
+	}
+	d, err := r.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSharedPreferences(d)
+}
+
+// SetPreferences writes shared_prefs/<name>.xml.
+// The app should be stopped, running apps keep preferences in memory and
+// overwrite the file.
+func (r *RunAs) SetPreferences(name string, prefs SharedPreferences) error {
+	p, err := prefsPath(name)
+	if err != nil {
+		return err
+	}
+	d, err := prefs.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := r.MkdirAll("shared_prefs"); err != nil {
+		return err
+	}
+	return r.WriteFile(p, d)
+}
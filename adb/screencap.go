@@ -2,9 +2,11 @@ package adb
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
 	"io"
+	"sync/atomic"
 )
 
 type PixFmt uint32
@@ -33,19 +35,34 @@ const (
 
 func (adb *ADB) Screencap() (*image.NRGBA, error) {
 	r, w := io.Pipe()
-	var gerr error
+	done := make(chan error, 1)
 	go func() {
-		gerr = adb.Run("screencap", w, nil)
+		err := adb.Run("screencap", w, nil)
 		w.Close()
+		done <- err
 	}()
 	img, err := decodeImageReader(r)
-	if gerr != nil {
+	if err != nil {
+		r.CloseWithError(err)
+	} else {
+		_, _ = io.Copy(io.Discard, r)
+	}
+	// wait for Run to finish, the client is not safe for concurrent use
+	if gerr := <-done; gerr != nil {
 		return nil, gerr
 	}
 	return img, err
 }
 
+// ScreencapContinuous streams screenshots to cb until it returns an error.
+// Run and Batch return an error matching ErrBusy while it is active, use a
+// separate client (see Session) to run other commands.
 func (adb *ADB) ScreencapContinuous(cb func(*image.NRGBA) error) error {
+	if !atomic.CompareAndSwapInt32(&adb.streaming, 0, 1) {
+		return &Error{Kind: ErrBusy, Err: errors.New("already streaming")}
+	}
+	defer atomic.StoreInt32(&adb.streaming, 0)
+
 	var img *image.NRGBA
 	err, reconnect := func() (error, bool) {
 		var err error
@@ -65,8 +82,8 @@ func (adb *ADB) ScreencapContinuous(cb func(*image.NRGBA) error) error {
 	}()
 
 	if err != nil && reconnect {
-		_ = adb.Close()
-		_ = adb.Init()
+		err = transportError(err, "")
+		adb.reconnect()
 	}
 
 	return err
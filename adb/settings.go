@@ -3,30 +3,156 @@ package adb
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-func (adb *ADB) Setting(namespace, property string) (string, error) {
+type Namespace string
+
+const (
+	System Namespace = "system"
+	Secure Namespace = "secure"
+	Global Namespace = "global"
+)
+
+// SettingKey identifies a single setting.
+type SettingKey struct {
+	Namespace Namespace
+	Key       string
+}
+
+func (k SettingKey) String() string { return string(k.Namespace) + "/" + k.Key }
+
+// settingNull is what `settings get` prints for a key that does not exist.
+const settingNull = "null"
+
+func (adb *ADB) Setting(namespace Namespace, property string) (string, error) {
 	buf := bytes.NewBuffer(nil)
 	err := adb.Run(
-		fmt.Sprintf("settings get '%s' '%s'", namespace, property),
+		fmt.Sprintf("settings get %s %s", Quote(string(namespace)), Quote(property)),
 		buf,
 		nil,
 	)
 	return strings.TrimSpace(buf.String()), err
 }
 
-func (adb *ADB) SetSetting(namespace, property, value string) error {
-	return adb.Run(
-		fmt.Sprintf("settings put '%s' '%s' '%s'", namespace, property, value),
-		nil,
-		nil,
+func (adb *ADB) SetSetting(namespace Namespace, property, value string) error {
+	return adb.runInput(setSettingCmd(namespace, property, value))
+}
+
+func setSettingCmd(namespace Namespace, property, value string) string {
+	return fmt.Sprintf(
+		"settings put %s %s %s",
+		Quote(string(namespace)),
+		Quote(property),
+		Quote(value),
 	)
 }
 
+func (adb *ADB) DeleteSetting(namespace Namespace, property string) error {
+	return adb.runInput(
+		fmt.Sprintf(
+			"settings delete %s %s >/dev/null",
+			Quote(string(namespace)),
+			Quote(property),
+		),
+	)
+}
+
+// ListSettings returns all key value pairs in the given namespace.
+// Values containing newlines are not supported.
+func (adb *ADB) ListSettings(namespace Namespace) (map[string]string, error) {
+	buf := bytes.NewBuffer(nil)
+	err := adb.Run(fmt.Sprintf("settings list %s", Quote(string(namespace))), buf, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseKV(buf.Bytes(), "="), nil
+}
+
+// SettingsSnapshot records the original values of settings so they can be
+// restored using Close.
+type SettingsSnapshot struct {
+	adb  *ADB
+	list []SettingKey
+	orig map[SettingKey]string
+}
+
+// Snapshot records the current values of the given keys.
+func (adb *ADB) Snapshot(keys ...SettingKey) (*SettingsSnapshot, error) {
+	s := &SettingsSnapshot{adb: adb, orig: make(map[SettingKey]string)}
+	return s, s.Record(keys...)
+}
+
+// Record adds keys to the snapshot, keys that are already recorded are
+// left untouched.
+func (s *SettingsSnapshot) Record(keys ...SettingKey) error {
+	for _, k := range keys {
+		if _, ok := s.orig[k]; ok {
+			continue
+		}
+		v, err := s.adb.Setting(k.Namespace, k.Key)
+		if err != nil {
+			return err
+		}
+		s.list = append(s.list, k)
+		s.orig[k] = v
+	}
+	return nil
+}
+
+// Set records the key if needed and changes its value.
+func (s *SettingsSnapshot) Set(namespace Namespace, property, value string) error {
+	if err := s.Record(SettingKey{namespace, property}); err != nil {
+		return err
+	}
+	return s.adb.SetSetting(namespace, property, value)
+}
+
+// Original returns the recorded value of k.
+func (s *SettingsSnapshot) Original(k SettingKey) (value string, ok bool) {
+	value, ok = s.orig[k]
+	return
+}
+
+// Keys returns the recorded keys, sorted.
+func (s *SettingsSnapshot) Keys() []SettingKey {
+	l := make([]SettingKey, len(s.list))
+	copy(l, s.list)
+	sort.Slice(l, func(i, j int) bool { return l[i].String() < l[j].String() })
+	return l
+}
+
+// Restore restores all recorded settings, in reverse order of recording.
+// Settings that did not exist are deleted.
+func (s *SettingsSnapshot) Restore() error {
+	var gerr error
+	for i := len(s.list) - 1; i >= 0; i-- {
+		k := s.list[i]
+		v := s.orig[k]
+		var err error
+		if v == settingNull {
+			err = s.adb.DeleteSetting(k.Namespace, k.Key)
+		} else {
+			err = s.adb.SetSetting(k.Namespace, k.Key, v)
+		}
+		if err != nil && gerr == nil {
+			gerr = err
+		}
+	}
+	return gerr
+}
+
+func (s *SettingsSnapshot) Close() error { return s.Restore() }
+
+var (
+	SettingBrightness  = SettingKey{System, "screen_brightness"}
+	SettingShowTouches = SettingKey{System, "show_touches"}
+)
+
 func (adb *ADB) Brightness() (int, error) {
-	v, err := adb.Setting("system", "screen_brightness")
+	v, err := adb.Setting(SettingBrightness.Namespace, SettingBrightness.Key)
 	if err != nil {
 		return 0, err
 	}
@@ -34,11 +160,11 @@ func (adb *ADB) Brightness() (int, error) {
 }
 
 func (adb *ADB) SetBrightness(n int) error {
-	return adb.SetSetting("system", "screen_brightness", strconv.Itoa(n))
+	return adb.SetSetting(SettingBrightness.Namespace, SettingBrightness.Key, strconv.Itoa(n))
 }
 
 func (adb *ADB) ShowTouches() (bool, error) {
-	v, err := adb.Setting("system", "show_touches")
+	v, err := adb.Setting(SettingShowTouches.Namespace, SettingShowTouches.Key)
 	return v == "1", err
 }
 
@@ -47,5 +173,5 @@ func (adb *ADB) SetShowTouches(n bool) error {
 	if n {
 		v = "1"
 	}
-	return adb.SetSetting("system", "show_touches", v)
+	return adb.SetSetting(SettingShowTouches.Namespace, SettingShowTouches.Key, v)
 }
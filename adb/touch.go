@@ -0,0 +1,557 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TouchDevice is an input device reporting multi-touch positions.
+type TouchDevice struct {
+	Path       string
+	Name       string
+	MinX, MaxX int
+	MinY, MaxY int
+}
+
+// Point converts raw axis values to display coordinates (natural
+// orientation) of a display of the given size.
+func (t TouchDevice) Point(rawX, rawY int, size image.Point) image.Point {
+	scale := func(v, min, max, size int) int {
+		if max <= min {
+			return v
+		}
+		return (v - min) * size / (max - min + 1)
+	}
+	return image.Pt(scale(rawX, t.MinX, t.MaxX, size.X), scale(rawY, t.MinY, t.MaxY, size.Y))
+}
+
+// Raw converts display coordinates back to raw axis values.
+func (t TouchDevice) Raw(p image.Point, size image.Point) (int, int) {
+	scale := func(v, min, max, size int) int {
+		if size <= 0 {
+			return v
+		}
+		return min + v*(max-min+1)/size
+	}
+	return scale(p.X, t.MinX, t.MaxX, size.X), scale(p.Y, t.MinY, t.MaxY, size.Y)
+}
+
+var (
+	geteventDeviceRE = regexp.MustCompile(`^add device \d+: (\S+)`)
+	geteventAbsRE    = regexp.MustCompile(`(ABS_MT_POSITION_[XY])\s*: value -?\d+, min (-?\d+), max (-?\d+)`)
+	geteventEventRE  = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s+(?:\S+:\s+)?(\w+)\s+(\w+)\s+(\w+)`)
+)
+
+// TouchDevices lists input devices that report multi-touch positions.
+func (adb *ADB) TouchDevices() ([]TouchDevice, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("getevent -lp 2>/dev/null", buf, nil); err != nil {
+		return nil, err
+	}
+
+	list := make([]TouchDevice, 0, 1)
+	var cur *TouchDevice
+	var hasX, hasY bool
+	add := func() {
+		if cur != nil && hasX && hasY {
+			list = append(list, *cur)
+		}
+	}
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if res := geteventDeviceRE.FindStringSubmatch(l); len(res) == 2 {
+			add()
+			cur, hasX, hasY = &TouchDevice{Path: res[1]}, false, false
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if strings.HasPrefix(l, "name:") {
+			cur.Name = strings.Trim(strings.TrimSpace(strings.TrimPrefix(l, "name:")), `"`)
+			continue
+		}
+		res := geteventAbsRE.FindStringSubmatch(l)
+		if len(res) != 4 {
+			continue
+		}
+		min, _ := strconv.Atoi(res[2])
+		max, _ := strconv.Atoi(res[3])
+		if res[1] == "ABS_MT_POSITION_X" {
+			cur.MinX, cur.MaxX, hasX = min, max, true
+			continue
+		}
+		cur.MinY, cur.MaxY, hasY = min, max, true
+	}
+	add()
+
+	return list, s.Err()
+}
+
+// TouchDevice returns the first touch device.
+func (adb *ADB) TouchDevice() (TouchDevice, error) {
+	list, err := adb.TouchDevices()
+	if err != nil {
+		return TouchDevice{}, err
+	}
+	if len(list) == 0 {
+		return TouchDevice{}, errors.New("no touchscreen found")
+	}
+	return list[0], nil
+}
+
+type GestureType string
+
+const (
+	GestureTap   GestureType = "tap"
+	GestureHold  GestureType = "hold"
+	GestureSwipe GestureType = "swipe"
+	GestureMulti GestureType = "multi"
+)
+
+// TouchPoint is a position at a time offset relative to the start of a
+// recording.
+type TouchPoint struct {
+	T time.Duration
+	X int
+	Y int
+}
+
+type jsonTouchPoint struct {
+	// T in milliseconds.
+	T float64 `json:"t"`
+	X int     `json:"x"`
+	Y int     `json:"y"`
+}
+
+func (t TouchPoint) MarshalJSON() ([]byte, error) {
+	ms := math.Round(float64(t.T)/float64(time.Microsecond)) / 1000
+	return json.Marshal(jsonTouchPoint{ms, t.X, t.Y})
+}
+
+func (t *TouchPoint) UnmarshalJSON(b []byte) error {
+	var j jsonTouchPoint
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	t.T = time.Duration(j.T * float64(time.Millisecond))
+	t.X, t.Y = j.X, j.Y
+	return nil
+}
+
+// Stroke is the path of a single finger.
+type Stroke []TouchPoint
+
+func (s Stroke) Start() time.Duration    { return s[0].T }
+func (s Stroke) End() time.Duration      { return s[len(s)-1].T }
+func (s Stroke) Duration() time.Duration { return s.End() - s.Start() }
+
+func (s Stroke) distance() float64 {
+	a, b := s[0], s[len(s)-1]
+	return math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y))
+}
+
+// Gesture is a group of strokes that overlap in time.
+type Gesture struct {
+	Type    GestureType `json:"type"`
+	Strokes []Stroke    `json:"strokes"`
+}
+
+func (g Gesture) Start() time.Duration {
+	var t time.Duration
+	for i, s := range g.Strokes {
+		if i == 0 || s.Start() < t {
+			t = s.Start()
+		}
+	}
+	return t
+}
+
+func (g Gesture) End() time.Duration {
+	var t time.Duration
+	for _, s := range g.Strokes {
+		if s.End() > t {
+			t = s.End()
+		}
+	}
+	return t
+}
+
+// tapSlop is the max distance in pixels a tap or hold may move.
+const tapSlop = 20
+
+func (g *Gesture) classify() {
+	switch {
+	case len(g.Strokes) > 1:
+		g.Type = GestureMulti
+	case g.Strokes[0].distance() > tapSlop:
+		g.Type = GestureSwipe
+	case g.Strokes[0].Duration() >= time.Millisecond*500:
+		g.Type = GestureHold
+	default:
+		g.Type = GestureTap
+	}
+}
+
+// Recording is the portable (json) format of recorded gestures.
+// Coordinates are display coordinates in the natural orientation of a
+// display of Size.
+type Recording struct {
+	Version  int         `json:"version"`
+	Size     image.Point `json:"size"`
+	Gestures []Gesture   `json:"gestures"`
+}
+
+func (r *Recording) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func ReadRecording(r io.Reader) (*Recording, error) {
+	rec := &Recording{}
+	if err := json.NewDecoder(r).Decode(rec); err != nil {
+		return nil, err
+	}
+	if rec.Version != 1 {
+		return nil, fmt.Errorf("unsupported recording version %d", rec.Version)
+	}
+	return rec, nil
+}
+
+// TouchRecorder reconstructs gestures from getevent.
+type TouchRecorder struct {
+	adb *ADB
+	dev TouchDevice
+	rec Recording
+
+	rw      sync.Mutex
+	stopped bool
+	kill    func()
+}
+
+// NewTouchRecorder detects the touchscreen and display size using client.
+func NewTouchRecorder(client *ADB) (*TouchRecorder, error) {
+	dev, err := client.TouchDevice()
+	if err != nil {
+		return nil, err
+	}
+	size, err := client.DisplaySize()
+	if err != nil {
+		return nil, err
+	}
+	return &TouchRecorder{adb: client, dev: dev, rec: Recording{Version: 1, Size: size}}, nil
+}
+
+// Recording returns the gestures recorded so far.
+func (t *TouchRecorder) Recording() *Recording {
+	t.rw.Lock()
+	defer t.rw.Unlock()
+	r := t.rec
+	r.Gestures = append([]Gesture{}, t.rec.Gestures...)
+	return &r
+}
+
+type touchSlot struct {
+	id     int
+	x, y   int
+	stroke Stroke
+	dirty  bool
+}
+
+// Run streams getevent and calls cb (optional) for each completed gesture
+// until Stop is called or getevent exits.
+func (t *TouchRecorder) Run(cb func(Gesture) error) error {
+	sh, err := t.adb.stream("getevent", "-lt", t.dev.Path)
+	if err != nil {
+		return err
+	}
+	var once sync.Once
+	kill := func() { once.Do(func() { _ = sh.kill() }) }
+	t.rw.Lock()
+	t.kill = kill
+	t.rw.Unlock()
+
+	slots := make(map[int]*touchSlot)
+	slot := 0
+	var start float64 = -1
+	var gesture []Stroke
+	active := func() int {
+		n := 0
+		for _, s := range slots {
+			if s.id >= 0 {
+				n++
+			}
+		}
+		return n
+	}
+
+	getSlot := func(n int) *touchSlot {
+		s, ok := slots[n]
+		if !ok {
+			s = &touchSlot{id: -1}
+			slots[n] = s
+		}
+		return s
+	}
+
+	handle := func(ts float64, typ, code, value string) error {
+		if start < 0 {
+			start = ts
+		}
+		offset := time.Duration((ts - start) * float64(time.Second))
+		v64, _ := strconv.ParseUint(value, 16, 32)
+		v := int(int32(uint32(v64)))
+
+		switch {
+		case typ == "EV_ABS" && code == "ABS_MT_SLOT":
+			slot = v
+		case typ == "EV_ABS" && code == "ABS_MT_TRACKING_ID":
+			s := getSlot(slot)
+			if v >= 0 {
+				s.id, s.stroke, s.dirty = v, nil, true
+				return nil
+			}
+			if s.id >= 0 && len(s.stroke) != 0 {
+				l := s.stroke[len(s.stroke)-1]
+				s.stroke = append(s.stroke, TouchPoint{T: offset, X: l.X, Y: l.Y})
+				gesture = append(gesture, s.stroke)
+			}
+			s.id, s.stroke = -1, nil
+		case typ == "EV_ABS" && code == "ABS_MT_POSITION_X":
+			s := getSlot(slot)
+			s.x, s.dirty = v, true
+		case typ == "EV_ABS" && code == "ABS_MT_POSITION_Y":
+			s := getSlot(slot)
+			s.y, s.dirty = v, true
+		case typ == "EV_SYN" && code == "SYN_REPORT":
+			for _, s := range slots {
+				if s.id < 0 || !s.dirty {
+					continue
+				}
+				s.dirty = false
+				p := t.dev.Point(s.x, s.y, t.rec.Size)
+				s.stroke = append(s.stroke, TouchPoint{T: offset, X: p.X, Y: p.Y})
+			}
+			if active() != 0 || len(gesture) == 0 {
+				return nil
+			}
+			g := Gesture{Strokes: gesture}
+			g.classify()
+			gesture = nil
+			t.rw.Lock()
+			t.rec.Gestures = append(t.rec.Gestures, g)
+			t.rw.Unlock()
+			if cb != nil {
+				return cb(g)
+			}
+		}
+		return nil
+	}
+
+	s := bufio.NewScanner(sh.stdout)
+	for s.Scan() {
+		res := geteventEventRE.FindStringSubmatch(s.Text())
+		if len(res) != 5 {
+			continue
+		}
+		ts, _ := strconv.ParseFloat(res[1], 64)
+		if err = handle(ts, res[2], res[3], res[4]); err != nil {
+			break
+		}
+	}
+
+	kill()
+	werr := sh.wait()
+	t.rw.Lock()
+	stopped := t.stopped
+	t.rw.Unlock()
+	if err != nil || stopped {
+		return err
+	}
+	if err = s.Err(); err != nil {
+		return err
+	}
+	return werr
+}
+
+// Stop stops Run.
+func (t *TouchRecorder) Stop() {
+	t.rw.Lock()
+	t.stopped = true
+	kill := t.kill
+	t.rw.Unlock()
+	if kill != nil {
+		kill()
+	}
+}
+
+// TouchPlayer replays a Recording.
+type TouchPlayer struct {
+	adb *ADB
+	// Speed scales playback speed, 2 plays twice as fast. Defaults to 1.
+	Speed float64
+	// Raw replays using sendevent on the recording device instead of
+	// Tap/Drag, which supports multi touch but requires root.
+	Raw bool
+}
+
+func NewTouchPlayer(client *ADB) *TouchPlayer {
+	return &TouchPlayer{adb: client, Speed: 1}
+}
+
+func (p *TouchPlayer) scale(d time.Duration) time.Duration {
+	if p.Speed <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) / p.Speed)
+}
+
+// Play replays all gestures with their original timing (scaled by Speed).
+// Coordinates are scaled if the display size differs from the recording.
+// When not using Raw, multi touch gestures are replayed one stroke at a
+// time.
+func (p *TouchPlayer) Play(r *Recording) error {
+	size, err := p.adb.DisplaySize()
+	if err != nil {
+		return err
+	}
+	pt := func(tp TouchPoint) image.Point {
+		if r.Size.X == 0 || r.Size.Y == 0 {
+			return image.Pt(tp.X, tp.Y)
+		}
+		return image.Pt(tp.X*size.X/r.Size.X, tp.Y*size.Y/r.Size.Y)
+	}
+
+	// Tap/Drag (and Call.Points) expect current coordinates unless the
+	// client translates them itself.
+	orient := func(q image.Point) image.Point { return q }
+	if _, ok := p.adb.Orientation(); !ok {
+		rot, err := p.adb.Rotation()
+		if err != nil {
+			return err
+		}
+		o := Orientation{Natural: size, Rotation: rot}
+		orient = o.Point
+	}
+
+	var dev TouchDevice
+	if p.Raw {
+		if dev, err = p.adb.TouchDevice(); err != nil {
+			return err
+		}
+	}
+
+	begin := time.Now()
+	for _, g := range r.Gestures {
+		if wait := p.scale(g.Start()) - time.Since(begin); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		if p.Raw {
+			if err := p.adb.RunScriptPrivileged(p.rawScript(dev, size, g, pt, orient)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, s := range g.Strokes {
+			a, b := orient(pt(s[0])), orient(pt(s[len(s)-1]))
+			switch g.Type {
+			case GestureTap:
+				err = p.adb.Tap(a.X, a.Y)
+			default:
+				err = p.adb.Drag(a.X, a.Y, b.X, b.Y, p.scale(s.Duration()))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *TouchPlayer) rawScript(dev TouchDevice, size image.Point, g Gesture, pt func(TouchPoint) image.Point, orient func(image.Point) image.Point) *Script {
+	type ev struct {
+		t    time.Duration
+		slot int
+		p    image.Point
+		up   bool
+		down bool
+	}
+	evs := make([]ev, 0)
+	for i, s := range g.Strokes {
+		for j, tp := range s {
+			evs = append(evs, ev{t: tp.T, slot: i, p: pt(tp), down: j == 0})
+		}
+		evs = append(evs, ev{t: s.End(), slot: i, up: true})
+	}
+	// stable insertion sort by time, keeps up after the last move
+	for i := 1; i < len(evs); i++ {
+		for j := i; j > 0 && evs[j].t < evs[j-1].t; j-- {
+			evs[j], evs[j-1] = evs[j-1], evs[j]
+		}
+	}
+
+	const (
+		evSyn = 0
+		evKey = 1
+		evAbs = 3
+
+		synReport = 0
+		btnTouch  = 0x14a
+
+		absMTSlot       = 0x2f
+		absMTPositionX  = 0x35
+		absMTPositionY  = 0x36
+		absMTTrackingID = 0x39
+	)
+
+	s := NewScript()
+	send := func(typ, code, value int) {
+		s.Raw(fmt.Sprintf("sendevent %s %d %d %d", Quote(dev.Path), typ, code, value))
+	}
+	down := 0
+	var last time.Duration = -1
+	for i, e := range evs {
+		if last >= 0 && e.t > last {
+			s.Sleep(p.scale(e.t - last))
+		}
+		last = e.t
+		send(evAbs, absMTSlot, e.slot)
+		switch {
+		case e.up:
+			send(evAbs, absMTTrackingID, -1)
+			down--
+			if down == 0 {
+				send(evKey, btnTouch, 0)
+			}
+		default:
+			if e.down {
+				send(evAbs, absMTTrackingID, i)
+				if down == 0 {
+					send(evKey, btnTouch, 1)
+				}
+				down++
+			}
+			s.points = append(s.points, orient(e.p))
+			x, y := dev.Raw(e.p, size)
+			send(evAbs, absMTPositionX, x)
+			send(evAbs, absMTPositionY, y)
+		}
+		send(evSyn, synReport, 0)
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package adb
+
+import (
+	"image"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// getevent -lp, trimmed
+const geteventDevices = `add device 1: /dev/input/event3
+  name:     "uinput-fpc"
+  events:
+    KEY (0001): KEY_KPENTER           KEY_UP                KEY_LEFT              KEY_RIGHT
+                KEY_DOWN              KEY_CAMERA
+  input props:
+    <none>
+add device 2: /dev/input/event2
+  name:     "fts_ts"
+  events:
+    KEY (0001): KEY_WAKEUP            BTN_TOOL_FINGER       BTN_TOUCH
+    ABS (0003): ABS_MT_SLOT           : value 0, min 0, max 9, fuzz 0, flat 0, resolution 0
+                ABS_MT_TOUCH_MAJOR    : value 0, min 0, max 255, fuzz 0, flat 0, resolution 0
+                ABS_MT_TOUCH_MINOR    : value 0, min 0, max 255, fuzz 0, flat 0, resolution 0
+                ABS_MT_POSITION_X     : value 0, min 0, max 1079, fuzz 0, flat 0, resolution 0
+                ABS_MT_POSITION_Y     : value 0, min 0, max 1919, fuzz 0, flat 0, resolution 0
+                ABS_MT_TRACKING_ID    : value 0, min 0, max 65535, fuzz 0, flat 0, resolution 0
+                ABS_MT_PRESSURE       : value 0, min 0, max 255, fuzz 0, flat 0, resolution 0
+  input props:
+    INPUT_PROP_DIRECT
+add device 3: /dev/input/event1
+  name:     "gpio-keys"
+  events:
+    KEY (0001): KEY_VOLUMEDOWN        KEY_VOLUMEUP          KEY_POWER
+  input props:
+    <none>
+`
+
+// getevent -lt /dev/input/event2: a tap, a two finger gesture, a swipe
+// reusing slot 0 and a hold
+const geteventEvents = `[     512.000000] EV_ABS       ABS_MT_TRACKING_ID   00000010
+[     512.000000] EV_ABS       ABS_MT_POSITION_X    00000064
+[     512.000000] EV_ABS       ABS_MT_POSITION_Y    000000c8
+[     512.000000] EV_ABS       ABS_MT_TOUCH_MAJOR   00000005
+[     512.000000] EV_KEY       BTN_TOUCH            DOWN
+[     512.000000] EV_SYN       SYN_REPORT           00000000
+[     512.062500] EV_ABS       ABS_MT_TRACKING_ID   ffffffff
+[     512.062500] EV_KEY       BTN_TOUCH            UP
+[     512.062500] EV_SYN       SYN_REPORT           00000000
+[     513.000000] EV_ABS       ABS_MT_TRACKING_ID   00000011
+[     513.000000] EV_ABS       ABS_MT_POSITION_X    0000012c
+[     513.000000] EV_ABS       ABS_MT_POSITION_Y    00000190
+[     513.000000] EV_KEY       BTN_TOUCH            DOWN
+[     513.000000] EV_SYN       SYN_REPORT           00000000
+[     513.125000] EV_ABS       ABS_MT_SLOT          00000001
+[     513.125000] EV_ABS       ABS_MT_TRACKING_ID   00000012
+[     513.125000] EV_ABS       ABS_MT_POSITION_X    00000258
+[     513.125000] EV_ABS       ABS_MT_POSITION_Y    00000190
+[     513.125000] EV_SYN       SYN_REPORT           00000000
+[     513.250000] EV_ABS       ABS_MT_SLOT          00000000
+[     513.250000] EV_ABS       ABS_MT_POSITION_X    000000fa
+[     513.250000] EV_ABS       ABS_MT_SLOT          00000001
+[     513.250000] EV_ABS       ABS_MT_POSITION_X    0000028a
+[     513.250000] EV_SYN       SYN_REPORT           00000000
+[     513.375000] EV_ABS       ABS_MT_SLOT          00000000
+[     513.375000] EV_ABS       ABS_MT_TRACKING_ID   ffffffff
+[     513.375000] EV_SYN       SYN_REPORT           00000000
+[     513.500000] EV_ABS       ABS_MT_SLOT          00000001
+[     513.500000] EV_ABS       ABS_MT_TRACKING_ID   ffffffff
+[     513.500000] EV_KEY       BTN_TOUCH            UP
+[     513.500000] EV_SYN       SYN_REPORT           00000000
+[     514.000000] EV_ABS       ABS_MT_SLOT          00000000
+[     514.000000] EV_ABS       ABS_MT_TRACKING_ID   00000013
+[     514.000000] EV_ABS       ABS_MT_POSITION_X    000001f4
+[     514.000000] EV_ABS       ABS_MT_POSITION_Y    000005dc
+[     514.000000] EV_KEY       BTN_TOUCH            DOWN
+[     514.000000] EV_SYN       SYN_REPORT           00000000
+[     514.062500] EV_ABS       ABS_MT_POSITION_Y    000003e8
+[     514.062500] EV_SYN       SYN_REPORT           00000000
+[     514.125000] EV_ABS       ABS_MT_POSITION_Y    000001f4
+[     514.125000] EV_SYN       SYN_REPORT           00000000
+[     514.187500] EV_ABS       ABS_MT_TRACKING_ID   ffffffff
+[     514.187500] EV_KEY       BTN_TOUCH            UP
+[     514.187500] EV_SYN       SYN_REPORT           00000000
+[     515.000000] EV_ABS       ABS_MT_TRACKING_ID   00000014
+[     515.000000] EV_ABS       ABS_MT_POSITION_X    00000320
+[     515.000000] EV_ABS       ABS_MT_POSITION_Y    00000384
+[     515.000000] EV_KEY       BTN_TOUCH            DOWN
+[     515.000000] EV_SYN       SYN_REPORT           00000000
+[     515.750000] EV_ABS       ABS_MT_TRACKING_ID   ffffffff
+[     515.750000] EV_KEY       BTN_TOUCH            UP
+[     515.750000] EV_SYN       SYN_REPORT           00000000
+`
+
+func fakeGetevent(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch {
+	case len(args) == 2 && args[1] == "-lp":
+		io.WriteString(stdout, geteventDevices)
+	case len(args) == 3 && args[1] == "-lt" && args[2] == "/dev/input/event2":
+		io.WriteString(stdout, geteventEvents)
+	default:
+		io.WriteString(stderr, "getevent: unexpected arguments\n")
+		return 1
+	}
+	return 0
+}
+
+func TestTouchDevices(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.Handle("getevent", fakeGetevent)
+	c := fakeClient(t, d)
+
+	list, err := c.TouchDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []TouchDevice{{Path: "/dev/input/event2", Name: "fts_ts", MaxX: 1079, MaxY: 1919}}
+	if !reflect.DeepEqual(list, exp) {
+		t.Errorf("expected %+v, got %+v", exp, list)
+	}
+}
+
+func TestTouchRecorder(t *testing.T) {
+	d := NewFakeDevice("fake", nil)
+	d.Handle("getevent", fakeGetevent)
+	c := fakeClient(t, d)
+
+	r, err := NewTouchRecorder(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gestures []Gesture
+	err = r.Run(func(g Gesture) error {
+		gestures = append(gestures, g)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := func(f float64) time.Duration { return time.Duration(f * float64(time.Millisecond)) }
+	exp := []Gesture{
+		{
+			Type:    GestureTap,
+			Strokes: []Stroke{{{0, 100, 200}, {ms(62.5), 100, 200}}},
+		},
+		{
+			// strokes are ordered by lift
+			Type: GestureMulti,
+			Strokes: []Stroke{
+				{{ms(1000), 300, 400}, {ms(1250), 250, 400}, {ms(1375), 250, 400}},
+				{{ms(1125), 600, 400}, {ms(1250), 650, 400}, {ms(1500), 650, 400}},
+			},
+		},
+		{
+			Type: GestureSwipe,
+			Strokes: []Stroke{{
+				{ms(2000), 500, 1500},
+				{ms(2062.5), 500, 1000},
+				{ms(2125), 500, 500},
+				{ms(2187.5), 500, 500},
+			}},
+		},
+		{
+			Type:    GestureHold,
+			Strokes: []Stroke{{{ms(3000), 800, 900}, {ms(3750), 800, 900}}},
+		},
+	}
+	if !reflect.DeepEqual(gestures, exp) {
+		t.Errorf("expected\n%+v\ngot\n%+v", exp, gestures)
+	}
+
+	rec := r.Recording()
+	if rec.Size != image.Pt(1080, 1920) {
+		t.Errorf("expected size 1080x1920, got %s", rec.Size)
+	}
+	if !reflect.DeepEqual(rec.Gestures, exp) {
+		t.Error("recording does not match the delivered gestures")
+	}
+}
@@ -0,0 +1,118 @@
+package adb
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"regexp"
+	"strconv"
+)
+
+// UINode is a node of the uiautomator view hierarchy. Bounds are in
+// current (rotated) screen coordinates.
+type UINode struct {
+	Text        string
+	ResourceID  string
+	Class       string
+	Package     string
+	ContentDesc string
+	Clickable   bool
+	Bounds      image.Rectangle
+	Children    []*UINode
+}
+
+// Find returns all nodes (depth first) for which match returns true.
+func (n *UINode) Find(match func(*UINode) bool) []*UINode {
+	list := make([]*UINode, 0)
+	var walk func(*UINode)
+	walk = func(n *UINode) {
+		if match(n) {
+			list = append(list, n)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return list
+}
+
+type xmlUINode struct {
+	Text        string       `xml:"text,attr"`
+	ResourceID  string       `xml:"resource-id,attr"`
+	Class       string       `xml:"class,attr"`
+	Package     string       `xml:"package,attr"`
+	ContentDesc string       `xml:"content-desc,attr"`
+	Clickable   string       `xml:"clickable,attr"`
+	Bounds      string       `xml:"bounds,attr"`
+	Nodes       []*xmlUINode `xml:"node"`
+}
+
+var boundsRE = regexp.MustCompile(`^\[(-?\d+),(-?\d+)\]\[(-?\d+),(-?\d+)\]$`)
+
+func (x *xmlUINode) node() *UINode {
+	n := &UINode{
+		Text:        x.Text,
+		ResourceID:  x.ResourceID,
+		Class:       x.Class,
+		Package:     x.Package,
+		ContentDesc: x.ContentDesc,
+		Clickable:   x.Clickable == "true",
+		Children:    make([]*UINode, len(x.Nodes)),
+	}
+	if res := boundsRE.FindStringSubmatch(x.Bounds); len(res) == 5 {
+		c := make([]int, 4)
+		for i := range c {
+			c[i], _ = strconv.Atoi(res[i+1])
+		}
+		n.Bounds = image.Rect(c[0], c[1], c[2], c[3])
+	}
+	for i, c := range x.Nodes {
+		n.Children[i] = c.node()
+	}
+	return n
+}
+
+// UIHierarchyXML dumps the current view hierarchy using uiautomator.
+func (adb *ADB) UIHierarchyXML() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := adb.Run("uiautomator dump /dev/tty 2>/dev/null", buf, nil); err != nil {
+		return nil, err
+	}
+	d := buf.Bytes()
+	if i := bytes.LastIndexByte(d, '>'); i >= 0 {
+		d = d[:i+1]
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(d), []byte("<?xml")) {
+		return nil, errors.New("uiautomator dump failed")
+	}
+	return d, nil
+}
+
+func (adb *ADB) UIHierarchy() (*UINode, error) {
+	d, err := adb.UIHierarchyXML()
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		Nodes []*xmlUINode `xml:"node"`
+	}
+	if err := xml.Unmarshal(d, &root); err != nil {
+		return nil, err
+	}
+	n := &UINode{Children: make([]*UINode, len(root.Nodes))}
+	for i, c := range root.Nodes {
+		n.Children[i] = c.node()
+	}
+	return n, nil
+}
+
+// TapNode taps the center of n.
+func (adb *ADB) TapNode(n *UINode) error {
+	p := n.Bounds.Min.Add(n.Bounds.Max).Div(2)
+	if adb.orient != nil {
+		p = adb.orient.Inverse(p)
+	}
+	return adb.Tap(p.X, p.Y)
+}
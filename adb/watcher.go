@@ -0,0 +1,249 @@
+package adb
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type AppEventType string
+
+const (
+	AppCrash AppEventType = "crash"
+	AppANR   AppEventType = "anr"
+	AppDied  AppEventType = "died"
+)
+
+type AppEvent struct {
+	Type    AppEventType
+	Package string
+	PID     int
+	Time    time.Time
+	Reason  string
+	// Stack holds the java stack trace for crashes, if available.
+	Stack string
+}
+
+var (
+	crashProcessRE = regexp.MustCompile(`^Process: ([^,]+), PID: (\d+)`)
+	anrRE          = regexp.MustCompile(`^ANR in (\S+)`)
+)
+
+// Watcher detects crashes, ANRs and deaths of a package by following
+// logcat (crash, events and system buffers).
+type Watcher struct {
+	adb  *ADB
+	pkg  string
+	rw   sync.Mutex
+	stop func()
+}
+
+func NewWatcher(client *ADB, pkg string) *Watcher {
+	return &Watcher{adb: client, pkg: pkg}
+}
+
+// eventFields splits an event log payload: [a,b,c].
+func eventFields(msg string) []string {
+	msg = strings.TrimSpace(msg)
+	msg = strings.TrimPrefix(msg, "[")
+	msg = strings.TrimSuffix(msg, "]")
+	return strings.Split(msg, ",")
+}
+
+// anrWindow is the time within which an am_anr event and an ActivityManager
+// 'ANR in' line are considered the same ANR.
+const anrWindow = time.Second * 10
+
+type crashAssembler struct {
+	active bool
+	ev     AppEvent
+	stack  []string
+}
+
+// Run follows logcat and calls cb for every event of the watched package
+// until Stop is called, cb returns an error or logcat exits.
+func (w *Watcher) Run(cb func(AppEvent) error) error {
+	cmd := w.adb.command(
+		"logcat",
+		"-v", "threadtime",
+		"-b", "crash",
+		"-b", "events",
+		"-b", "system",
+		"-T", "1",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var once sync.Once
+	var stopped bool
+	kill := func() { once.Do(func() { _ = cmd.Process.Kill() }) }
+	w.rw.Lock()
+	w.stop = func() {
+		w.rw.Lock()
+		stopped = true
+		w.rw.Unlock()
+		kill()
+	}
+	w.rw.Unlock()
+
+	lines := make(chan string, 64)
+	go func() {
+		s := bufio.NewScanner(stdout)
+		s.Buffer(make([]byte, 0, 1024*64), 1024*1024)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		close(lines)
+	}()
+
+	// last delivered ANR, am_anr and 'ANR in' are both logged for the same
+	// ANR on most versions, whichever comes first is delivered.
+	var anrTime time.Time
+	var anrEvent bool
+	anr := func(ev AppEvent, event bool) error {
+		if !anrTime.IsZero() && anrEvent != event {
+			if d := ev.Time.Sub(anrTime); d > -anrWindow && d < anrWindow {
+				return nil
+			}
+		}
+		anrTime, anrEvent = ev.Time, event
+		return cb(ev)
+	}
+
+	var crash crashAssembler
+	flush := func() error {
+		if !crash.active {
+			return nil
+		}
+		crash.active = false
+		ev := crash.ev
+		ev.Stack = strings.Join(crash.stack, "\n")
+		crash.stack = nil
+		if ev.Package != w.pkg {
+			return nil
+		}
+		return cb(ev)
+	}
+
+	handle := func(l LogLine) error {
+		if crash.active && (l.Tag != "AndroidRuntime" || l.PID != crash.ev.PID) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		switch l.Tag {
+		case "AndroidRuntime":
+			if strings.HasPrefix(l.Message, "FATAL EXCEPTION") {
+				if err := flush(); err != nil {
+					return err
+				}
+				crash.active = true
+				crash.ev = AppEvent{Type: AppCrash, PID: l.PID, Time: l.Time}
+				return nil
+			}
+			if !crash.active {
+				return nil
+			}
+			if res := crashProcessRE.FindStringSubmatch(l.Message); len(res) == 3 {
+				crash.ev.Package = res[1]
+				crash.ev.PID, _ = strconv.Atoi(res[2])
+				return nil
+			}
+			if crash.ev.Reason == "" {
+				crash.ev.Reason = l.Message
+			}
+			crash.stack = append(crash.stack, l.Message)
+
+		case "am_anr":
+			// [user,pid,package,flags,reason]
+			f := eventFields(l.Message)
+			if len(f) < 3 || f[2] != w.pkg {
+				return nil
+			}
+			pid, _ := strconv.Atoi(f[1])
+			ev := AppEvent{Type: AppANR, Package: f[2], PID: pid, Time: l.Time}
+			if len(f) > 4 {
+				ev.Reason = strings.Join(f[4:], ",")
+			}
+			return anr(ev, true)
+
+		case "ActivityManager":
+			// fallback, am_anr is not logged on all versions.
+			res := anrRE.FindStringSubmatch(l.Message)
+			if len(res) != 2 || res[1] != w.pkg {
+				return nil
+			}
+			return anr(AppEvent{Type: AppANR, Package: w.pkg, Time: l.Time, Reason: l.Message}, false)
+
+		case "am_proc_died":
+			// [user,pid,process,...]
+			f := eventFields(l.Message)
+			if len(f) < 3 || f[2] != w.pkg {
+				return nil
+			}
+			pid, _ := strconv.Atoi(f[1])
+			return cb(AppEvent{Type: AppDied, Package: f[2], PID: pid, Time: l.Time})
+		}
+
+		return nil
+	}
+
+	err = func() error {
+		for {
+			var l string
+			var ok bool
+			select {
+			case l, ok = <-lines:
+			case <-time.After(time.Millisecond * 200):
+				// the crash buffer has no terminator, flush once it's idle
+				if err := flush(); err != nil {
+					return err
+				}
+				continue
+			}
+			if !ok {
+				return flush()
+			}
+			line, ok := ParseLogLine(l)
+			if !ok {
+				continue
+			}
+			if err := handle(line); err != nil {
+				return err
+			}
+		}
+	}()
+
+	kill()
+	for range lines {
+	}
+	werr := cmd.Wait()
+
+	w.rw.Lock()
+	w.stop = nil
+	stop := stopped
+	w.rw.Unlock()
+	if err != nil || stop {
+		return err
+	}
+	return werr
+}
+
+// Stop stops Run.
+func (w *Watcher) Stop() {
+	w.rw.Lock()
+	stop := w.stop
+	w.rw.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
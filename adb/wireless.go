@@ -0,0 +1,328 @@
+package adb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Device is an entry of `adb devices`.
+type Device struct {
+	Serial string
+	// State is one of device, offline, unauthorized, ...
+	State string
+}
+
+func (d Device) Online() bool { return d.State == "device" }
+
+// DeviceList returns all devices known to the adb server, regardless of
+// their state.
+func DeviceList(executable string) ([]Device, error) {
+	d, err := host(executable, "devices")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Device, 0)
+	s := bufio.NewScanner(strings.NewReader(d))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) < 2 || strings.HasPrefix(s.Text(), "List of devices") || f[0] == "*" {
+			continue
+		}
+		list = append(list, Device{Serial: f[0], State: f[1]})
+	}
+	return list, s.Err()
+}
+
+// host runs an adb host command and returns its stdout.
+func host(executable string, args ...string) (string, error) {
+	cmd := exec.Command(executable, args...)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		o := strings.TrimSpace(stderr.String())
+		if o == "" {
+			o = strings.TrimSpace(string(out))
+		}
+		if kind := classify(o); kind != nil {
+			err = &Error{Kind: kind, Output: o, Err: err}
+		} else if o != "" {
+			err = fmt.Errorf("%w: %s", err, o)
+		}
+	}
+	return strings.TrimSpace(string(out)), err
+}
+
+// Connect connects to a device over tcp/ip (host:port).
+func Connect(executable, addr string) error {
+	_, err := connect(executable, addr)
+	return err
+}
+
+// connect is Connect reporting whether adb was already connected to addr,
+// in which case the device might still be offline or unauthorized.
+func connect(executable, addr string) (already bool, err error) {
+	out, err := host(executable, "connect", addr)
+	if err != nil {
+		return false, err
+	}
+	// adb exits 0 even if it fails to connect.
+	if !strings.Contains(out, "connected to") || strings.Contains(out, "failed") {
+		return false, fmt.Errorf("connect %s: %s", addr, out)
+	}
+	return strings.Contains(out, "already connected"), nil
+}
+
+// Disconnect disconnects a tcp/ip device, all if addr is empty.
+func Disconnect(executable, addr string) error {
+	args := []string{"disconnect"}
+	if addr != "" {
+		args = append(args, addr)
+	}
+	_, err := host(executable, args...)
+	return err
+}
+
+// Pair pairs with a device using a wireless debugging pairing code,
+// requires android 11+.
+func Pair(executable, addr, code string) error {
+	out, err := host(executable, "pair", addr, code)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, "Successfully paired") {
+		return fmt.Errorf("pair %s: %s", addr, out)
+	}
+	return nil
+}
+
+// MDNSService is an entry of `adb mdns services`.
+type MDNSService struct {
+	Name    string
+	Service string
+	Addr    string
+}
+
+func MDNSServices(executable string) ([]MDNSService, error) {
+	d, err := host(executable, "mdns", "services")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]MDNSService, 0)
+	s := bufio.NewScanner(strings.NewReader(d))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) != 3 || !strings.Contains(f[2], ":") {
+			continue
+		}
+		list = append(list, MDNSService{Name: f[0], Service: f[1], Addr: f[2]})
+	}
+	return list, s.Err()
+}
+
+// TCPIP restarts adbd on the device listening on the given tcp port.
+// The client needs to be reinitialized afterwards.
+func (adb *ADB) TCPIP(port int) error {
+	out, err := adb.command("tcpip", strconv.Itoa(port)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// WifiIP returns the ipv4 address of wlan0.
+func (adb *ADB) WifiIP() (string, error) {
+	v, err := adb.grep("ip -f inet addr show wlan0 2>/dev/null | grep -m 1 inet")
+	if err != nil {
+		return "", err
+	}
+	f := strings.Fields(v)
+	if len(f) < 2 {
+		return "", errors.New("wlan0 has no ipv4 address")
+	}
+	return strings.SplitN(f[1], "/", 2)[0], nil
+}
+
+// NetworkDevice is a device the Supervisor keeps connected.
+type NetworkDevice struct {
+	Addr string
+	// MDNSName, if set, is used to discover the device's new address when
+	// it changed (e.g. adb-<serial>-AbCdEf).
+	MDNSName string
+}
+
+type SupervisorEventType string
+
+const (
+	DeviceConnected     SupervisorEventType = "connected"
+	DeviceDisconnected  SupervisorEventType = "disconnected"
+	DeviceAddrChanged   SupervisorEventType = "addr-changed"
+	DeviceConnectFailed SupervisorEventType = "connect-failed"
+)
+
+type SupervisorEvent struct {
+	Type    SupervisorEventType
+	Device  NetworkDevice
+	OldAddr string
+	Err     error
+}
+
+// Supervisor periodically reconnects a list of network devices.
+type Supervisor struct {
+	bin      string
+	interval time.Duration
+
+	// check serializes Check, rw guards the fields below.
+	check    sync.Mutex
+	rw       sync.Mutex
+	devices  []NetworkDevice
+	online   map[int]bool
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewSupervisor(executable string, interval time.Duration, devices ...NetworkDevice) *Supervisor {
+	return &Supervisor{
+		bin:      executable,
+		interval: interval,
+		devices:  devices,
+		online:   make(map[int]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Devices returns the supervised devices with their current addresses.
+func (s *Supervisor) Devices() []NetworkDevice {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	l := make([]NetworkDevice, len(s.devices))
+	copy(l, s.devices)
+	return l
+}
+
+// Check runs a single supervision pass, cb is called once the pass is
+// complete.
+func (s *Supervisor) Check(cb func(SupervisorEvent)) error {
+	s.check.Lock()
+	defer s.check.Unlock()
+
+	devs, err := DeviceList(s.bin)
+	if err != nil {
+		return err
+	}
+	state := make(map[string]string, len(devs))
+	for _, d := range devs {
+		state[d.Serial] = d.State
+	}
+
+	s.rw.Lock()
+	devices := make([]NetworkDevice, len(s.devices))
+	copy(devices, s.devices)
+	online := make(map[int]bool, len(s.online))
+	for i, v := range s.online {
+		online[i] = v
+	}
+	s.rw.Unlock()
+
+	var events []SupervisorEvent
+	var mdns []MDNSService
+	for i := range devices {
+		d := &devices[i]
+		if state[d.Addr] == "device" {
+			if !online[i] {
+				online[i] = true
+				events = append(events, SupervisorEvent{Type: DeviceConnected, Device: *d})
+			}
+			continue
+		}
+
+		if online[i] {
+			online[i] = false
+			events = append(events, SupervisorEvent{Type: DeviceDisconnected, Device: *d})
+		}
+
+		if d.MDNSName != "" {
+			if mdns == nil {
+				mdns, _ = MDNSServices(s.bin)
+			}
+			for _, m := range mdns {
+				if m.Name != d.MDNSName || m.Addr == d.Addr {
+					continue
+				}
+				old := d.Addr
+				if state[old] != "" {
+					_ = Disconnect(s.bin, old)
+				}
+				d.Addr = m.Addr
+				events = append(events, SupervisorEvent{Type: DeviceAddrChanged, Device: *d, OldAddr: old})
+				break
+			}
+		}
+
+		if st := state[d.Addr]; st != "" && st != "device" {
+			// offline or unauthorized, adb connect would only report
+			// 'already connected'
+			_ = Disconnect(s.bin, d.Addr)
+		}
+		if err := s.connect(d.Addr); err != nil {
+			events = append(events, SupervisorEvent{Type: DeviceConnectFailed, Device: *d, Err: err})
+			continue
+		}
+		online[i] = true
+		events = append(events, SupervisorEvent{Type: DeviceConnected, Device: *d})
+	}
+
+	s.rw.Lock()
+	s.devices, s.online = devices, online
+	s.rw.Unlock()
+
+	for _, ev := range events {
+		cb(ev)
+	}
+
+	return nil
+}
+
+// connect connects to addr, if adb reports it was already connected the
+// device list must confirm it is online.
+func (s *Supervisor) connect(addr string) error {
+	already, err := connect(s.bin, addr)
+	if err != nil || !already {
+		return err
+	}
+	devs, err := DeviceList(s.bin)
+	if err != nil {
+		return err
+	}
+	for _, d := range devs {
+		if d.Serial == addr && d.State == "device" {
+			return nil
+		}
+	}
+	return fmt.Errorf("connect %s: already connected but not online", addr)
+}
+
+// Run calls Check every interval until Stop is called.
+func (s *Supervisor) Run(cb func(SupervisorEvent)) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if err := s.Check(cb); err != nil {
+			return err
+		}
+		select {
+		case <-s.stop:
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Supervisor) Stop() { s.stopOnce.Do(func() { close(s.stop) }) }
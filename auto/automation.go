@@ -6,6 +6,8 @@ import (
 	"image/color"
 	"sort"
 	"sync"
+
+	"github.com/frizinak/autodroid/adb"
 )
 
 func ToGray(img *image.NRGBA) *image.Gray {
@@ -132,6 +134,9 @@ type ImageSearch struct {
 	b    image.Rectangle
 	pix  map[string]*pixel
 	tess interface{}
+	rot  adb.Rotation
+
+	onSet []func(*image.NRGBA)
 }
 
 func NewImageSearch() *ImageSearch {
@@ -149,12 +154,48 @@ func (i *ImageSearch) Set(img *image.NRGBA) {
 	i.c = img
 	i.b = img.Bounds()
 	i.g = nil
+	for _, h := range i.onSet {
+		h(img)
+	}
 }
 
+// OnSet registers a handler that is called with each image passed to Set.
+func (i *ImageSearch) OnSet(h func(*image.NRGBA)) { i.onSet = append(i.onSet, h) }
+
+// Image returns the image passed to Set.
+func (i *ImageSearch) Image() *image.NRGBA { return i.c }
+
 func (i *ImageSearch) Bounds() image.Rectangle {
 	return i.b
 }
 
+// SetRotation sets the rotation of the images passed to Set relative to
+// the natural orientation of the device. Relative, FromNatural and
+// ToNatural use it to map between both coordinate spaces so regions
+// authored in the natural orientation keep working after a rotation.
+func (i *ImageSearch) SetRotation(r adb.Rotation) { i.rot = r }
+func (i *ImageSearch) Rotation() adb.Rotation     { return i.rot }
+
+func (i *ImageSearch) orientation() adb.Orientation {
+	o := adb.Orientation{Natural: i.b.Size(), Rotation: i.rot}
+	if i.rot.Landscape() {
+		o.Natural = image.Pt(o.Natural.Y, o.Natural.X)
+	}
+	return o
+}
+
+// FromNatural converts a rectangle in natural coordinates to image
+// coordinates.
+func (i *ImageSearch) FromNatural(r image.Rectangle) image.Rectangle {
+	return i.orientation().Rect(r.Sub(i.b.Min)).Add(i.b.Min)
+}
+
+// ToNatural converts a rectangle in image coordinates to natural
+// coordinates.
+func (i *ImageSearch) ToNatural(r image.Rectangle) image.Rectangle {
+	return i.orientation().InverseRect(r.Sub(i.b.Min)).Add(i.b.Min)
+}
+
 func (i *ImageSearch) SubImage(r image.Rectangle) *image.NRGBA {
 	return i.c.SubImage(r).(*image.NRGBA)
 }
@@ -186,14 +227,17 @@ func RelativeRect(x0, y0, x1, y1 float64) RR {
 	return r
 }
 
+// Relative converts a relative rectangle in the natural orientation to
+// image coordinates.
 func (i *ImageSearch) Relative(r RR) image.Rectangle {
-	n := i.b
-	w, h := float64(i.b.Dx()), float64(i.b.Dy())
+	var n image.Rectangle
+	size := i.orientation().Natural
+	w, h := float64(size.X), float64(size.Y)
 	n.Min.X = int(r.Min.X * w)
 	n.Min.Y = int(r.Min.Y * h)
 	n.Max.X = int(r.Max.X * w)
 	n.Max.Y = int(r.Max.Y * h)
-	return n
+	return i.FromNatural(n.Add(i.b.Min))
 }
 
 type pixel struct {
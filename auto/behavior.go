@@ -48,11 +48,45 @@ func GetOrTest(t Test, search *ImageSearch, results Results, s *Stats) bool {
 }
 
 type Behaviors struct {
-	list  []Behavior
-	state *State
-	stats *Stats
+	list    []Behavior
+	state   *State
+	stats   *Stats
+	onError []ErrorHandler
+	onStep  []StepHandler
 }
 
+// ErrorHandler is called with errors returned by Behaviors.Do and the
+// search it was called with.
+type ErrorHandler func(error, *ImageSearch)
+
+// Action is the decision taken by a Behavior.
+type Action string
+
+const (
+	ActionRun      Action = "run"
+	ActionFallback Action = "fallback"
+	ActionSkip     Action = "skip"
+)
+
+// Decision is the action taken by the Behavior at index Behavior in the
+// list passed to NewBehaviors.
+type Decision struct {
+	Behavior int    `json:"behavior"`
+	Action   Action `json:"action"`
+}
+
+// Step describes a single call to Behaviors.Do.
+type Step struct {
+	Search    *ImageSearch
+	Results   Results
+	Decisions []Decision
+	Stopped   bool
+	Err       error
+}
+
+// StepHandler is called after each call to Behaviors.Do.
+type StepHandler func(Step)
+
 type Stats struct {
 	list     []ID
 	tests    map[ID]int
@@ -85,9 +119,31 @@ func (s *Stats) Info(id ID) (tests, cached int, duration time.Duration) {
 }
 
 func (s *Stats) List() []ID {
+	if s == nil {
+		return nil
+	}
 	return s.list
 }
 
+// StatsEntry is a snapshot of the stats of a single test.
+type StatsEntry struct {
+	ID       ID            `json:"id"`
+	Tests    int           `json:"tests"`
+	Cached   int           `json:"cached"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Entries returns a snapshot of all stats in the order tests were first
+// run.
+func (s *Stats) Entries() []StatsEntry {
+	list := make([]StatsEntry, 0, len(s.List()))
+	for _, id := range s.List() {
+		t, c, d := s.Info(id)
+		list = append(list, StatsEntry{id, t, c, d})
+	}
+	return list
+}
+
 func NewBehaviors(stats bool, behaviors ...Behavior) *Behaviors {
 	var s *Stats
 	if stats {
@@ -103,8 +159,30 @@ func NewBehaviors(stats bool, behaviors ...Behavior) *Behaviors {
 
 func (b *Behaviors) Stats() *Stats { return b.stats }
 
+// OnError registers a handler that is called for each error returned by Do.
+func (b *Behaviors) OnError(h ErrorHandler) { b.onError = append(b.onError, h) }
+
+// OnStep registers a handler that is called after each call to Do.
+func (b *Behaviors) OnStep(h StepHandler) { b.onStep = append(b.onStep, h) }
+
 func (b *Behaviors) Do(search *ImageSearch) error {
+	step := Step{Search: search}
+	err := b.do(search, &step)
+	step.Err = err
+	for _, h := range b.onStep {
+		h(step)
+	}
+	if err != nil {
+		for _, h := range b.onError {
+			h(err, search)
+		}
+	}
+	return err
+}
+
+func (b *Behaviors) do(search *ImageSearch, step *Step) error {
 	results := NewResults()
+	step.Results = results
 	state := b.state
 	var err error
 	b.state, err = state.DoNext(b.stats, search, results)
@@ -113,17 +191,21 @@ func (b *Behaviors) Do(search *ImageSearch) error {
 	}
 
 	if b.state.Stopped() {
+		step.Stopped = true
 		return nil
 	}
 
-	for _, bh := range b.list {
-		if err := bh.Do(b.stats, b.state, search, results); err != nil {
+	for i, bh := range b.list {
+		action, err := bh.decide(b.stats, b.state, search, results)
+		step.Decisions = append(step.Decisions, Decision{Behavior: i, Action: action})
+		if err != nil {
 			return err
 		}
 		if err := b.state.DoImmediate(b.stats, search, results); err != nil {
 			return err
 		}
 		if b.state.Stopped() {
+			step.Stopped = true
 			break
 		}
 	}
@@ -150,7 +232,7 @@ type SubImgTest struct {
 func (s SubImgTest) ID() ID { return s.Uniq }
 
 func (s SubImgTest) Test(stats *Stats, search *ImageSearch, res Results) bool {
-	sr := search.Search(s.Region, s.Img, s.Tolerance)
+	sr := search.Search(search.FromNatural(s.Region), s.Img, s.Tolerance)
 	var r Result
 	if len(sr) != 0 {
 		r.Match = true
@@ -342,13 +424,18 @@ type Behavior struct {
 }
 
 func (b Behavior) Do(stats *Stats, state *State, search *ImageSearch, results Results) error {
+	_, err := b.decide(stats, state, search, results)
+	return err
+}
+
+func (b Behavior) decide(stats *Stats, state *State, search *ImageSearch, results Results) (Action, error) {
 	if !b.Test(stats, search, results) {
 		if b.Fallback != nil {
-			return b.Fallback(state, search, results)
+			return ActionFallback, b.Fallback(state, search, results)
 		}
-		return nil
+		return ActionSkip, nil
 	}
-	return b.Run(state, search, results)
+	return ActionRun, b.Run(state, search, results)
 }
 
 func NewBehavior(tests []Test, run Runner) Behavior {
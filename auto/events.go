@@ -0,0 +1,94 @@
+package auto
+
+import (
+	"sync"
+	"time"
+
+	"github.com/frizinak/autodroid/adb"
+)
+
+// EventTest matches when an app event (crash, ANR, death) was pushed,
+// typically from an adb.Watcher running in another goroutine:
+//
+//	go watcher.Run(func(ev adb.AppEvent) error { test.Push(ev); return nil })
+//
+// Each successful test consumes one event. Events for a process that
+// already had an event pushed (e.g. the death following a crash) are
+// dropped.
+type EventTest struct {
+	Uniq ID
+
+	rw       sync.Mutex
+	pending  []adb.AppEvent
+	last     adb.AppEvent
+	pushed   adb.AppEvent
+	suppress time.Time
+}
+
+func NewEventTest(id ID) *EventTest {
+	return &EventTest{Uniq: id}
+}
+
+func (e *EventTest) ID() ID { return e.Uniq }
+
+// Push queues an event, safe for concurrent use.
+func (e *EventTest) Push(ev adb.AppEvent) {
+	e.rw.Lock()
+	defer e.rw.Unlock()
+	if time.Now().Before(e.suppress) {
+		return
+	}
+	if ev.PID != 0 && ev.PID == e.pushed.PID && ev.Package == e.pushed.Package {
+		return
+	}
+	e.pushed = ev
+	e.pending = append(e.pending, ev)
+}
+
+// Suppress drops pending events and ignores events pushed during d.
+func (e *EventTest) Suppress(d time.Duration) {
+	e.rw.Lock()
+	e.pending = nil
+	e.suppress = time.Now().Add(d)
+	e.rw.Unlock()
+}
+
+// Event returns the event consumed by the last matching Test.
+func (e *EventTest) Event() adb.AppEvent {
+	e.rw.Lock()
+	defer e.rw.Unlock()
+	return e.last
+}
+
+func (e *EventTest) Test(stats *Stats, search *ImageSearch, res Results) bool {
+	e.rw.Lock()
+	var r Result
+	if len(e.pending) != 0 {
+		e.last = e.pending[0]
+		e.pending = e.pending[1:]
+		r.Match = true
+	}
+	e.rw.Unlock()
+	res.Set(e, r)
+	return r.Match
+}
+
+// RecoveryCooldown is the time events are suppressed after a recovery so
+// the deaths caused by e.g. AmRestart don't trigger another recovery.
+const RecoveryCooldown = time.Second * 10
+
+// NewRecoveryBehavior returns a behavior that calls recover for each event
+// pushed to t and stops the current iteration. Events are suppressed for
+// RecoveryCooldown once recover returns.
+// Pass it as the first behavior to NewBehaviors so it takes precedence.
+func NewRecoveryBehavior(t *EventTest, recover func(adb.AppEvent) error) Behavior {
+	return NewBehavior(
+		[]Test{t},
+		func(state *State, search *ImageSearch, results Results) error {
+			state.Stop()
+			err := recover(t.Event())
+			t.Suppress(RecoveryCooldown)
+			return err
+		},
+	)
+}
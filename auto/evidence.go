@@ -0,0 +1,178 @@
+package auto
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/frizinak/autodroid/adb"
+)
+
+// Evidence captures the state of a device into a timestamped zip file for
+// post-mortem debugging.
+type Evidence struct {
+	ADB *adb.ADB
+	Dir string
+
+	// LogLines is the amount of logcat lines to include, defaults to 1000.
+	LogLines int
+	// Settings to include besides the battery state.
+	Settings []adb.SettingKey
+}
+
+func NewEvidence(client *adb.ADB, dir string) *Evidence {
+	return &Evidence{ADB: client, Dir: dir, LogLines: 1000}
+}
+
+// Capture writes a zip file containing:
+// the current screen (or the image in search if not nil), the last logcat
+// lines, dumpsys activity top, the ui hierarchy, battery state, settings,
+// stats and reason.
+// Parts that fail to be captured are replaced by a .error file.
+// The client must not be in ScreencapContinuous, all parts would fail with
+// adb.ErrBusy, use a separate session for evidence instead.
+// Returns the path of the zip file.
+func (e *Evidence) Capture(reason error, search *ImageSearch, stats *Stats) (string, error) {
+	if err := os.MkdirAll(e.Dir, 0o755); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := filepath.Join(
+		e.Dir,
+		fmt.Sprintf("evidence-%s-%03d.zip", now.Format("20060102-150405"), now.Nanosecond()/1e6),
+	)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	z := zip.NewWriter(f)
+	add := func(name string, cb func(w io.Writer) error) error {
+		buf := bytes.NewBuffer(nil)
+		if err := cb(buf); err != nil {
+			w, zerr := z.Create(name + ".error")
+			if zerr != nil {
+				return zerr
+			}
+			_, zerr = io.WriteString(w, err.Error())
+			return zerr
+		}
+		w, err := z.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, buf)
+		return err
+	}
+	encode := func(v interface{}) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(v)
+		}
+	}
+	run := func(cmd string) func(w io.Writer) error {
+		return func(w io.Writer) error { return e.ADB.Run(cmd, w, nil) }
+	}
+
+	lines := e.LogLines
+	if lines <= 0 {
+		lines = 1000
+	}
+
+	parts := []struct {
+		name string
+		cb   func(w io.Writer) error
+	}{
+		{"reason.txt", func(w io.Writer) error {
+			if reason == nil {
+				_, err := io.WriteString(w, "manual capture\n")
+				return err
+			}
+			_, err := fmt.Fprintln(w, reason.Error())
+			return err
+		}},
+		{"screen.png", func(w io.Writer) error {
+			var img image.Image
+			if search != nil && search.Image() != nil {
+				img = search.Image()
+			} else {
+				i, err := e.ADB.Screencap()
+				if err != nil {
+					return err
+				}
+				img = i
+			}
+			return png.Encode(w, img)
+		}},
+		{"logcat.txt", func(w io.Writer) error {
+			d, err := e.ADB.LogcatTail(lines)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(d)
+			return err
+		}},
+		{"activity-top.txt", run("dumpsys activity top")},
+		{"ui.xml", func(w io.Writer) error {
+			d, err := e.ADB.UIHierarchyXML()
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(d)
+			return err
+		}},
+		{"battery.json", func(w io.Writer) error {
+			b, err := e.ADB.Battery()
+			if err != nil {
+				return err
+			}
+			return encode(b)(w)
+		}},
+		{"settings.json", func(w io.Writer) error {
+			m := make(map[string]string, len(e.Settings))
+			for _, k := range e.Settings {
+				v, err := e.ADB.Setting(k.Namespace, k.Key)
+				if err != nil {
+					return err
+				}
+				m[k.String()] = v
+			}
+			return encode(m)(w)
+		}},
+		{"stats.json", func(w io.Writer) error {
+			return encode(stats.Entries())(w)
+		}},
+	}
+
+	for _, p := range parts {
+		if err = add(p.name, p.cb); err != nil {
+			break
+		}
+	}
+
+	if zerr := z.Close(); err == nil {
+		err = zerr
+	}
+	if ferr := f.Close(); err == nil {
+		err = ferr
+	}
+	return path, err
+}
+
+// Attach captures evidence for every error returned by b.Do.
+// Errors while capturing are passed to onErr if not nil.
+func (e *Evidence) Attach(b *Behaviors, onErr func(error)) {
+	b.OnError(func(err error, search *ImageSearch) {
+		if _, cerr := e.Capture(err, search, b.Stats()); cerr != nil && onErr != nil {
+			onErr(cerr)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package auto
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frizinak/autodroid/adb"
+)
+
+// Device holds the per device instances of an Orchestrator.
+type Device struct {
+	Serial  string
+	Capture *adb.ADB
+	Input   *adb.ADB
+	Search  *ImageSearch
+	Log     *log.Logger
+}
+
+// Factory creates the behaviors for a device. It is called each time the
+// loop of a device is (re)started and may configure d.Input (middleware,
+// orientation, ...).
+type Factory func(d *Device) (*Behaviors, error)
+
+// DeviceStatus describes the loop of a single device.
+type DeviceStatus struct {
+	Serial  string
+	Running bool
+	// Starts is the amount of times the loop was started.
+	Starts int
+	// Iterations is the amount of calls to Behaviors.Do over all starts,
+	// Stats only cover the current (or last) start.
+	Iterations int
+	// Err is the error that ended the previous run of the loop.
+	Err   error
+	Stats []StatsEntry
+}
+
+var errStopped = errors.New("stopped")
+
+type deviceLoop struct {
+	serial string
+	stop   chan struct{}
+
+	// rw is never held while running Do, stats is a snapshot of the
+	// behaviors' Stats taken after each iteration.
+	rw           sync.Mutex
+	stats        []StatsEntry
+	running      bool
+	disconnected bool
+	starts       int
+	iterations   int
+	err          error
+	ended        time.Time
+}
+
+// Orchestrator runs the same behaviors on all connected devices
+// concurrently. A device's loop is started when it comes online, stopped
+// when it goes offline and restarted when it reconnects.
+type Orchestrator struct {
+	// Interval between device list polls, defaults to 5s.
+	Interval time.Duration
+	// Sleep between frames.
+	Sleep time.Duration
+	// RestartDelay is the time to wait before restarting a loop that
+	// ended with an error while its device stayed online, defaults to 5s.
+	RestartDelay time.Duration
+	// CaptureBuffer and InputBuffer are the maxBuffer of the capture and
+	// input clients, default to 30MiB and 1MiB.
+	CaptureBuffer int
+	InputBuffer   int
+
+	// List and Client default to adb.DeviceList and adb.New for the
+	// executable passed to NewOrchestrator, override them to e.g. use
+	// adb.FakeDevice clients.
+	List   func() ([]adb.Device, error)
+	Client func(serial string, maxBuffer int) *adb.ADB
+
+	factory Factory
+	logw    io.Writer
+	log     *log.Logger
+
+	rw       sync.Mutex
+	loops    map[string]*deviceLoop
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewOrchestrator creates an orchestrator, each device logs to logw
+// prefixed with its serial.
+func NewOrchestrator(executable string, factory Factory, logw io.Writer) *Orchestrator {
+	return &Orchestrator{
+		Interval:      time.Second * 5,
+		RestartDelay:  time.Second * 5,
+		CaptureBuffer: 1024 * 1024 * 30,
+		InputBuffer:   1024 * 1024,
+		List:          func() ([]adb.Device, error) { return adb.DeviceList(executable) },
+		Client: func(serial string, maxBuffer int) *adb.ADB {
+			return adb.New(executable, serial, maxBuffer)
+		},
+
+		factory: factory,
+		logw:    logw,
+		log:     log.New(logw, "", log.LstdFlags),
+		loops:   make(map[string]*deviceLoop),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Check polls the device list once, starting and stopping loops as needed.
+func (o *Orchestrator) Check() error {
+	devs, err := o.List()
+	if err != nil {
+		return err
+	}
+	online := make(map[string]struct{}, len(devs))
+	for _, d := range devs {
+		if d.Online() {
+			online[d.Serial] = struct{}{}
+		}
+	}
+
+	o.rw.Lock()
+	defer o.rw.Unlock()
+	select {
+	case <-o.stop:
+		return nil
+	default:
+	}
+
+	for serial := range online {
+		l, ok := o.loops[serial]
+		if !ok {
+			l = &deviceLoop{serial: serial}
+			o.loops[serial] = l
+		}
+		l.rw.Lock()
+		start := !l.running && (l.starts == 0 || l.disconnected || time.Since(l.ended) >= o.RestartDelay)
+		if start {
+			if l.disconnected {
+				o.log.Printf("%s: reconnected", serial)
+			}
+			l.running, l.disconnected = true, false
+			l.starts++
+			l.stop = make(chan struct{})
+		}
+		l.rw.Unlock()
+		if start {
+			o.wg.Add(1)
+			go o.run(l)
+		}
+	}
+
+	for serial, l := range o.loops {
+		if _, ok := online[serial]; ok {
+			continue
+		}
+		l.rw.Lock()
+		if !l.disconnected {
+			o.log.Printf("%s: disconnected", serial)
+			l.disconnected = true
+			if l.running {
+				close(l.stop)
+			}
+		}
+		l.rw.Unlock()
+	}
+
+	return nil
+}
+
+// Run polls the device list every Interval until Stop is called and waits
+// for all loops to end.
+func (o *Orchestrator) Run() error {
+	t := time.NewTicker(o.Interval)
+	defer t.Stop()
+	for {
+		if err := o.Check(); err != nil {
+			o.log.Println(err)
+		}
+		select {
+		case <-o.stop:
+			o.wg.Wait()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Stop stops all loops, they end after their current iteration.
+func (o *Orchestrator) Stop() {
+	o.rw.Lock()
+	defer o.rw.Unlock()
+	o.stopOnce.Do(func() { close(o.stop) })
+	for _, l := range o.loops {
+		l.rw.Lock()
+		if l.running && !l.disconnected {
+			close(l.stop)
+		}
+		l.disconnected = true
+		l.rw.Unlock()
+	}
+}
+
+// Status returns the status of all devices seen so far, sorted by serial.
+func (o *Orchestrator) Status() []DeviceStatus {
+	o.rw.Lock()
+	loops := make([]*deviceLoop, 0, len(o.loops))
+	for _, l := range o.loops {
+		loops = append(loops, l)
+	}
+	o.rw.Unlock()
+	sort.Slice(loops, func(i, j int) bool { return loops[i].serial < loops[j].serial })
+
+	list := make([]DeviceStatus, len(loops))
+	for i, l := range loops {
+		l.rw.Lock()
+		list[i] = DeviceStatus{
+			Serial:     l.serial,
+			Running:    l.running,
+			Starts:     l.starts,
+			Iterations: l.iterations,
+			Err:        l.err,
+			Stats:      l.stats,
+		}
+		l.rw.Unlock()
+	}
+	return list
+}
+
+func (o *Orchestrator) run(l *deviceLoop) {
+	defer o.wg.Done()
+	logger := log.New(o.logw, fmt.Sprintf("%s: ", l.serial), log.LstdFlags)
+	logger.Println("starting")
+
+	err := o.loop(l, logger)
+	if errors.Is(err, errStopped) {
+		err = nil
+	}
+	if err != nil {
+		logger.Printf("stopped: %s", err)
+	} else {
+		logger.Println("stopped")
+	}
+
+	l.rw.Lock()
+	l.running, l.err, l.ended = false, err, time.Now()
+	l.rw.Unlock()
+}
+
+func (o *Orchestrator) loop(l *deviceLoop, logger *log.Logger) error {
+	l.rw.Lock()
+	stop := l.stop
+	l.rw.Unlock()
+
+	capture := o.Client(l.serial, o.CaptureBuffer)
+	if err := capture.Init(); err != nil {
+		return err
+	}
+	defer capture.Close()
+	input := o.Client(l.serial, o.InputBuffer)
+	if err := input.Init(); err != nil {
+		return err
+	}
+	defer input.Close()
+
+	d := &Device{
+		Serial:  l.serial,
+		Capture: capture,
+		Input:   input,
+		Search:  NewImageSearch(),
+		Log:     logger,
+	}
+	defer d.Search.Close()
+
+	b, err := o.factory(d)
+	if err != nil {
+		return err
+	}
+	l.rw.Lock()
+	l.stats = b.Stats().Entries()
+	l.rw.Unlock()
+
+	return capture.ScreencapContinuous(func(img *image.NRGBA) error {
+		select {
+		case <-stop:
+			return errStopped
+		default:
+		}
+
+		d.Search.Set(img)
+		err := b.Do(d.Search)
+		stats := b.Stats().Entries()
+		l.rw.Lock()
+		l.iterations++
+		l.stats = stats
+		l.rw.Unlock()
+		if err != nil {
+			return err
+		}
+
+		if o.Sleep > 0 {
+			select {
+			case <-stop:
+				return errStopped
+			case <-time.After(o.Sleep):
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,345 @@
+package auto
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frizinak/autodroid/adb"
+)
+
+const sessionFile = "session.json"
+
+func framePath(n int) string { return fmt.Sprintf("frames/%06d.png", n) }
+
+// SessionCommand is an adb command issued during a recorded session.
+type SessionCommand struct {
+	Time   time.Time `json:"time"`
+	Helper string    `json:"helper"`
+	Cmd    string    `json:"cmd"`
+	Input  bool      `json:"input"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// SessionStep is a single recorded call to Behaviors.Do.
+type SessionStep struct {
+	Time time.Time `json:"time"`
+	// Frame is the index of the frame last passed to ImageSearch.Set,
+	// -1 if none.
+	Frame     int          `json:"frame"`
+	Rotation  adb.Rotation `json:"rotation"`
+	Results   Results      `json:"results"`
+	Decisions []Decision   `json:"decisions"`
+	Stopped   bool         `json:"stopped"`
+	Error     string       `json:"error,omitempty"`
+	// Commands issued since the previous step.
+	Commands []SessionCommand `json:"commands"`
+}
+
+type sessionIndex struct {
+	Version int           `json:"version"`
+	Frames  int           `json:"frames"`
+	Steps   []SessionStep `json:"steps"`
+}
+
+// Recorder writes frames, results, behavior decisions and adb commands of
+// an automation run to a zip archive, see OpenSession and Replay.
+type Recorder struct {
+	rw      sync.Mutex
+	f       *os.File
+	z       *zip.Writer
+	enc     png.Encoder
+	frames  int
+	pending []SessionCommand
+	steps   []SessionStep
+	err     error
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		f:   f,
+		z:   zip.NewWriter(f),
+		enc: png.Encoder{CompressionLevel: png.BestSpeed},
+	}, nil
+}
+
+// Attach records the frames passed to search (including its current image),
+// each step of b and the commands run by client, client is optional.
+func (r *Recorder) Attach(b *Behaviors, search *ImageSearch, client *adb.ADB) {
+	if img := search.Image(); img != nil {
+		r.Frame(img)
+	}
+	search.OnSet(r.Frame)
+	b.OnStep(r.Step)
+	if client != nil {
+		client.Use(r.Middleware())
+	}
+}
+
+// Frame records an image passed to ImageSearch.Set.
+func (r *Recorder) Frame(img *image.NRGBA) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+	if r.err != nil {
+		return
+	}
+	w, err := r.z.Create(framePath(r.frames))
+	if err == nil {
+		err = r.enc.Encode(w, img)
+	}
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.frames++
+}
+
+// Step records a call to Behaviors.Do.
+func (r *Recorder) Step(s Step) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+	step := SessionStep{
+		Time:      time.Now(),
+		Frame:     r.frames - 1,
+		Rotation:  s.Search.Rotation(),
+		Results:   make(Results, len(s.Results)),
+		Decisions: append([]Decision{}, s.Decisions...),
+		Stopped:   s.Stopped,
+		Commands:  r.pending,
+	}
+	for id, res := range s.Results {
+		step.Results[id] = res
+	}
+	if s.Err != nil {
+		step.Error = s.Err.Error()
+	}
+	r.pending = nil
+	r.steps = append(r.steps, step)
+}
+
+// Middleware records all commands run by the client it is added to.
+func (r *Recorder) Middleware() adb.Middleware {
+	return func(call *adb.Call, next func() error) error {
+		err := next()
+		c := SessionCommand{
+			Time:   call.Start,
+			Helper: call.Helper,
+			Cmd:    call.Cmd,
+			Input:  call.Input,
+		}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		r.rw.Lock()
+		r.pending = append(r.pending, c)
+		r.rw.Unlock()
+		return err
+	}
+}
+
+// Close writes the session index and closes the archive.
+func (r *Recorder) Close() error {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+	err := r.err
+	if err == nil {
+		var w io.Writer
+		w, err = r.z.Create(sessionFile)
+		if err == nil {
+			err = json.NewEncoder(w).Encode(sessionIndex{Version: 1, Frames: r.frames, Steps: r.steps})
+		}
+	}
+	if zerr := r.z.Close(); err == nil {
+		err = zerr
+	}
+	if ferr := r.f.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// Session is a recorded session archive.
+type Session struct {
+	Steps []SessionStep
+
+	z      *zip.ReadCloser
+	frames int
+}
+
+func OpenSession(path string) (*Session, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := z.Open(sessionFile)
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+	defer f.Close()
+
+	var index sessionIndex
+	if err := json.NewDecoder(f).Decode(&index); err != nil {
+		z.Close()
+		return nil, err
+	}
+	if index.Version != 1 {
+		z.Close()
+		return nil, fmt.Errorf("unsupported session version %d", index.Version)
+	}
+	return &Session{Steps: index.Steps, z: z, frames: index.Frames}, nil
+}
+
+// Frames returns the amount of recorded frames.
+func (s *Session) Frames() int { return s.frames }
+
+// Frame decodes the nth recorded frame.
+func (s *Session) Frame(n int) (*image.NRGBA, error) {
+	f, err := s.z.Open(framePath(n))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba, nil
+	}
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return nrgba, nil
+}
+
+func (s *Session) Close() error { return s.z.Close() }
+
+// Difference is a mismatch between a recorded and a replayed step.
+type Difference struct {
+	Step     int
+	What     string
+	Recorded string
+	Replayed string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("step %d: %s: recorded %s, replayed %s", d.Step, d.What, d.Recorded, d.Replayed)
+}
+
+func resultString(r Result) string {
+	if !r.Match {
+		return "no match"
+	}
+	return fmt.Sprintf("match %dx%d+%d+%d", r.Dx(), r.Dy(), r.Min.X, r.Min.Y)
+}
+
+// Replay feeds the recorded frames through b and returns where its
+// results and decisions differ from the recording.
+// Runners are executed as usual so b should be created with a client in
+// dry run mode (see adb.DryRun) or connected to an adb.FakeDevice.
+// search may be nil. Steps recorded before the first frame are replayed
+// as long as search has no image, otherwise they are reported as skipped.
+func Replay(s *Session, b *Behaviors, search *ImageSearch) ([]Difference, error) {
+	if search == nil {
+		search = NewImageSearch()
+		defer search.Close()
+	}
+
+	var last Step
+	n := len(b.onStep)
+	b.OnStep(func(s Step) { last = s })
+	defer func() {
+		b.onStep[n] = nil
+		b.onStep = b.onStep[:n]
+	}()
+
+	diffs := make([]Difference, 0)
+	frame := -1
+	for i, rec := range s.Steps {
+		if rec.Frame < 0 && search.Image() != nil {
+			diffs = append(diffs, Difference{Step: i, What: "frame", Recorded: "none", Replayed: "skipped"})
+			continue
+		}
+		if rec.Frame >= 0 && rec.Frame != frame {
+			img, err := s.Frame(rec.Frame)
+			if err != nil {
+				return diffs, err
+			}
+			search.Set(img)
+			frame = rec.Frame
+		}
+		search.SetRotation(rec.Rotation)
+
+		_ = b.Do(search)
+		diffs = append(diffs, diffStep(i, rec, last)...)
+	}
+
+	return diffs, nil
+}
+
+func diffStep(n int, rec SessionStep, step Step) []Difference {
+	diffs := make([]Difference, 0)
+	add := func(what, recorded, replayed string) {
+		if recorded != replayed {
+			diffs = append(diffs, Difference{Step: n, What: what, Recorded: recorded, Replayed: replayed})
+		}
+	}
+
+	errString := ""
+	if step.Err != nil {
+		errString = step.Err.Error()
+	}
+	add("error", rec.Error, errString)
+	add("stopped", fmt.Sprint(rec.Stopped), fmt.Sprint(step.Stopped))
+
+	ids := make(map[ID]struct{}, len(rec.Results))
+	for id := range rec.Results {
+		ids[id] = struct{}{}
+	}
+	for id := range step.Results {
+		ids[id] = struct{}{}
+	}
+	list := make([]string, 0, len(ids))
+	for id := range ids {
+		list = append(list, string(id))
+	}
+	sort.Strings(list)
+	result := func(r Results, id ID) string {
+		res, ok := r[id]
+		if !ok {
+			return "untested"
+		}
+		return resultString(res)
+	}
+	for _, id := range list {
+		add(fmt.Sprintf("result %s", id), result(rec.Results, ID(id)), result(step.Results, ID(id)))
+	}
+
+	decisions := len(rec.Decisions)
+	if len(step.Decisions) > decisions {
+		decisions = len(step.Decisions)
+	}
+	decision := func(d []Decision, i int) string {
+		if i >= len(d) {
+			return "none"
+		}
+		return string(d[i].Action)
+	}
+	for i := 0; i < decisions; i++ {
+		add(fmt.Sprintf("behavior %d", i), decision(rec.Decisions, i), decision(step.Decisions, i))
+	}
+
+	return diffs
+}
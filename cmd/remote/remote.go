@@ -16,8 +16,10 @@ const ADB = "adb"
 func main() {
 	var sleep float64
 	var dev string
+	var dry bool
 	flag.Float64Var(&sleep, "i", 0, "sleep interval in seconds (float)")
 	flag.StringVar(&dev, "d", "", "device serial")
+	flag.BoolVar(&dry, "n", false, "dry-run: log input instead of sending it to the device")
 	flag.Parse()
 
 	if dev == "" {
@@ -44,6 +46,14 @@ func main() {
 		panic(err)
 	}
 	defer input.Close()
+	if dry {
+		input.Use(adb.NewDryRun(func(c adb.Call) {
+			log.Printf("dry-run %s: %s", c.Helper, c.Cmd)
+		}).Middleware())
+	}
+	if err := input.SetOrientationAware(true); err != nil {
+		log.Println(err)
+	}
 
 	app := New(input, log.New(os.Stderr, "", 0))
 	imgs := make(chan *image.NRGBA, 2)
@@ -123,8 +123,9 @@ func (r *App) onText(w *glfw.Window, char rune) {
 func (r *App) onMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
 	if button != glfw.MouseButton1 {
 		if action == glfw.Press {
+			r.updateOrientation()
 			t := r.TranslateCoords(r.cursorPos)
-			r.log.Println(t)
+			r.log.Println(t, r.inputCoords(t))
 		}
 		return
 	}
@@ -134,14 +135,15 @@ func (r *App) onMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw
 		r.mouseDownPos = r.cursorPos
 	} else if action == glfw.Release {
 		r.mouseDown = false
-		t := r.TranslateCoords(r.cursorPos)
+		r.updateOrientation()
+		t := r.inputCoords(r.TranslateCoords(r.cursorPos))
 		since := time.Since(r.mouseDownTime)
 		if since <= time.Millisecond*150 {
 			if err := r.adb.TapQuick(t.X, t.Y); err != nil {
 				r.log.Println(err)
 			}
 		} else {
-			f := r.TranslateCoords(r.mouseDownPos)
+			f := r.inputCoords(r.TranslateCoords(r.mouseDownPos))
 			if err := r.adb.Drag(f.X, f.Y, t.X, t.Y, since); err != nil {
 				r.log.Println(err)
 			}
@@ -164,6 +166,25 @@ func (r *App) TranslateCoords(i FPoint) image.Point {
 	return image.Point{int(scale * (i.X - xoffset)), int(scale * (i.Y - yoffset))}
 }
 
+// updateOrientation refreshes the rotation of an orientation aware adb
+// client, called on each click as 0/180 and 90/270 rotations can not be
+// detected from the screenshot size.
+func (r *App) updateOrientation() {
+	if err := r.adb.UpdateOrientation(); err != nil {
+		r.log.Println(err)
+	}
+}
+
+// inputCoords converts image coordinates to the (natural orientation)
+// coordinates expected by an orientation aware adb client.
+func (r *App) inputCoords(p image.Point) image.Point {
+	o, ok := r.adb.Orientation()
+	if !ok {
+		return p
+	}
+	return o.Inverse(p)
+}
+
 func (r *App) onCursor(w *glfw.Window, x, y float64) {
 	r.cursorPos.X, r.cursorPos.Y = x, y
 }